@@ -3,7 +3,7 @@ package claudesdk
 import (
 	"context"
 	"fmt"
-	"os"
+	"sync"
 )
 
 // Client for bidirectional, interactive conversations with Claude Code.
@@ -32,10 +32,24 @@ import (
 //   - Fire-and-forget automation scripts
 //   - When all inputs are known upfront
 //   - Stateless operations
+//
+// A Client is safe for concurrent use. Its lifecycle is described by
+// ClientState.
 type Client struct {
-	options   *ClaudeCodeOptions
-	transport Transport
-	connected bool
+	options *ClaudeCodeOptions
+
+	mu         sync.Mutex
+	state      ClientState
+	transport  Transport
+	dispatcher *dispatcher   // single reader of the transport's messages
+	err        error         // why the client failed
+	streamErr  error         // first skipped line of the last session
+	drained    chan struct{} // closed when Disconnect finishes draining
+	listeners  []func(from, to ClientState)
+	changes    []stateChange // transitions not yet reported to listeners
+	notifying  bool
+
+	sendMu sync.Mutex // keeps turns in the order prompts are sent
 }
 
 // NewClient creates a new Claude SDK client
@@ -43,9 +57,7 @@ func NewClient(options *ClaudeCodeOptions) *Client {
 	if options == nil {
 		options = NewClaudeCodeOptions()
 	}
-	
-	os.Setenv("CLAUDE_CODE_ENTRYPOINT", "sdk-go-client")
-	
+
 	return &Client{
 		options: options,
 	}
@@ -53,11 +65,60 @@ func NewClient(options *ClaudeCodeOptions) *Client {
 
 // Connect connects to Claude with a prompt or message stream
 // If prompt is nil, connects with an empty stream for interactive use
+//
+// The CLI subprocess lives until Disconnect or until ctx is cancelled,
+// whichever comes first. Connecting an already connected Client does
+// nothing; a closed or failed Client starts a new CLI process. Connect
+// returns a *ClientStateError while another Connect or a Disconnect is in
+// progress.
 func (c *Client) Connect(ctx context.Context, prompt interface{}) error {
-	if c.connected {
+	c.mu.Lock()
+	switch c.state {
+	case ClientConnected:
+		c.mu.Unlock()
 		return nil
+	case ClientConnecting, ClientDraining:
+		err := c.stateError("Connect")
+		c.mu.Unlock()
+		return err
+	}
+	previous := c.transport
+	c.transport = nil
+	c.dispatcher = nil
+	c.err = nil
+	c.streamErr = nil
+	c.setState(ClientConnecting)
+	c.mu.Unlock()
+	c.notify()
+
+	// Release whatever the last session left behind
+	if previous != nil {
+		previous.Disconnect()
 	}
 
+	t, d, err := c.connect(ctx, prompt)
+
+	c.mu.Lock()
+	if err != nil {
+		c.err = err
+		c.setState(ClientFailed)
+	} else {
+		c.transport = t
+		c.dispatcher = d
+		c.setState(ClientConnected)
+	}
+	c.mu.Unlock()
+	c.notify()
+	if err != nil {
+		return err
+	}
+
+	go c.watch(t)
+	return nil
+}
+
+// connect starts the CLI and the dispatcher reading its output
+func (c *Client) connect(ctx context.Context, prompt interface{}) (Transport, *dispatcher, error) {
 	// Auto-connect with empty channel if no prompt is provided
 	if prompt == nil {
 		emptyChan := make(chan map[string]interface{})
@@ -68,74 +129,248 @@ func (c *Client) Connect(ctx context.Context, prompt interface{}) error {
 	// Create subprocess transport
 	t, err := NewSubprocessCLITransport(prompt, c.options, "", false)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
+	t.entrypoint = "sdk-go-client"
 
-	c.transport = t
-	
-	if err := c.transport.Connect(); err != nil {
-		return err
+	if err := t.ConnectContext(ctx); err != nil {
+		t.Disconnect()
+		return nil, nil, err
 	}
 
-	c.connected = true
-	return nil
+	// Start reading right away so the CLI is never blocked on output
+	d, err := newDispatcher(t, c.options)
+	if err != nil {
+		t.Disconnect()
+		return nil, nil, err
+	}
+	return t, d, nil
 }
 
-// ReceiveMessages receives all messages from Claude
-func (c *Client) ReceiveMessages(ctx context.Context) (<-chan Message, error) {
-	if !c.connected {
-		return nil, NewCLIConnectionError("Not connected. Call Connect() first.")
+// watch moves a connected Client to Failed, or Closed after a clean exit,
+// when its CLI exits without Disconnect
+func (c *Client) watch(t Transport) {
+	<-t.Done()
+
+	c.mu.Lock()
+	if c.transport == t && c.state == ClientConnected {
+		if err := t.Err(); err != nil {
+			c.err = err
+			c.setState(ClientFailed)
+		} else {
+			c.setState(ClientClosed)
+		}
 	}
+	c.mu.Unlock()
+	c.notify()
+}
+
+// session returns the transport for op, which needs a connected Client
+func (c *Client) session(op string) (Transport, *dispatcher, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state != ClientConnected {
+		return nil, nil, c.notConnected(op)
+	}
+	return c.transport, c.dispatcher, nil
+}
 
-	dataChan, err := c.transport.ReceiveMessages()
+// subscribe attaches a subscription to the session's messages. A session
+// that has ended on its own can still be subscribed to, so messages held
+// from it and the error that ended it are not lost.
+func (c *Client) subscribe(ctx context.Context, claim, stopAfterResult bool) (*Subscription, error) {
+	d, err := c.dispatch()
 	if err != nil {
 		return nil, err
 	}
+	return d.subscribe(ctx, claim, stopAfterResult), nil
+}
 
-	msgChan := make(chan Message)
-	
-	go func() {
-		defer close(msgChan)
-		
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case data, ok := <-dataChan:
-				if !ok {
-					return
-				}
-				
-				// Convert MessageData to map for parser
-				dataMap := messageDataToMap(data)
-				msg, err := ParseMessage(dataMap)
-				if err != nil {
-					// Log error and continue
-					continue
-				}
-				
-				select {
-				case msgChan <- msg:
-				case <-ctx.Done():
-					return
-				}
-			}
-		}
-	}()
+// dispatch returns the dispatcher to subscribe to
+func (c *Client) dispatch() (*dispatcher, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dispatcher == nil {
+		return nil, c.notConnected("receive messages")
+	}
+	return c.dispatcher, nil
+}
 
-	return msgChan, nil
+// Subscribe attaches an observer to the session's messages. A Client reads
+// the CLI's output once and fans it out, so any number of subscriptions,
+// such as a UI and a logger, each see every message that arrives while
+// they are subscribed, in order, buffered independently. An observer never
+// takes the messages a Client holds for its receivers (ReceiveMessages,
+// ReceiveResponse and their iterator forms), so attaching a logger doesn't
+// use up a response.
+//
+// The subscription ends when the session ends, when ctx is done, on
+// Unsubscribe, or with *BufferOverflowError if it falls more than
+// MessageBufferSize messages behind.
+//
+// Example:
+//
+//	sub, err := client.Subscribe(ctx)
+//	if err != nil {
+//	    return err
+//	}
+//	defer sub.Unsubscribe()
+//	for msg := range sub.Messages() {
+//	    log.Printf("%T", msg)
+//	}
+func (c *Client) Subscribe(ctx context.Context) (*Subscription, error) {
+	return c.subscribe(ctx, false, false)
+}
+
+// ReceiveMessages receives all messages from Claude
+//
+// Each call is a separate receiver that lasts until ctx is done, so cancel
+// ctx once you stop reading. Messages that arrive while no receiver is
+// attached are held, up to MessageBufferSize with the oldest dropped
+// first, and the next receiver gets them.
+//
+// Messages that fail to parse are handled according to the options'
+// ParseErrorPolicy. In strict mode the channel is closed at the first
+// failure; use OnParseError or ReceiveMessagesIter to observe the error.
+func (c *Client) ReceiveMessages(ctx context.Context) (<-chan Message, error) {
+	sub, err := c.subscribe(ctx, true, false)
+	if err != nil {
+		return nil, err
+	}
+	return sub.Messages(), nil
 }
 
 // Query sends a new request in streaming mode
+//
+// Its answer arrives through ReceiveResponse or ReceiveMessages. To receive
+// exactly the messages that answer this prompt, use Send instead.
+//
+// sessionID is stamped on the outgoing messages (default "default"). The
+// CLI keeps a single conversation per process and replies with its own
+// session ID, so it does not separate conversations; use one Client per
+// conversation.
 func (c *Client) Query(ctx context.Context, prompt interface{}, sessionID string) error {
-	if !c.connected {
-		return NewCLIConnectionError("Not connected. Call Connect() first.")
+	_, err := c.send(ctx, prompt, sessionID, false)
+	return err
+}
+
+// Send sends a new request in streaming mode and returns a handle to its
+// answer. The CLI answers prompts one at a time, so prompts sent while
+// earlier ones are in progress wait their turn, and each Turn receives only
+// the messages for its own prompt. Subscribers still see every message.
+//
+// Prompts passed to Connect are not tracked, so Send the first prompt only
+// after their answers have arrived. The turn is abandoned when ctx is done.
+// sessionID is treated as in Query.
+//
+// Example:
+//
+//	first, err := client.Send(ctx, "What is 2 + 2?", "")
+//	if err != nil {
+//	    return err
+//	}
+//	second, err := client.Send(ctx, "What is 10% of 80?", "")
+//	if err != nil {
+//	    return err
+//	}
+//	for msg := range first.Messages() {
+//	    fmt.Println(msg)
+//	}
+//	result, err := second.Wait(ctx)
+func (c *Client) Send(ctx context.Context, prompt interface{}, sessionID string) (*Turn, error) {
+	sub, err := c.send(ctx, prompt, sessionID, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Turn{sub: sub}, nil
+}
+
+// Ask sends a prompt and waits for the whole answer, collected into a
+// Response. A failed turn, such as one that hit the turn limit, is
+// returned along with its error.
+//
+// Example:
+//
+//	resp, err := client.Ask(ctx, "What is 2 + 2?")
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Println(resp.Text())
+func (c *Client) Ask(ctx context.Context, prompt interface{}) (*Response, error) {
+	turn, err := c.Send(ctx, prompt, "")
+	if err != nil {
+		return nil, err
+	}
+	defer turn.Close()
+
+	var messages []Message
+	for msg := range turn.Messages() {
+		messages = append(messages, msg)
 	}
+	resp := newResponse(messages)
+	if err := turn.Err(); err != nil {
+		return resp, err
+	}
+	return resp, resp.Result().Err()
+}
 
+// send writes a prompt to the CLI and records the turn it starts. With
+// track it returns the turn's subscription.
+func (c *Client) send(ctx context.Context, prompt interface{}, sessionID string, track bool) (*Subscription, error) {
 	if sessionID == "" {
 		sessionID = "default"
 	}
 
+	messages, err := promptMessages(prompt, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each user message is answered with one ResultMessage
+	results := 0
+	for _, msg := range messages {
+		if msg.Type == "user" {
+			results++
+		}
+	}
+	if results == 0 {
+		if track {
+			return nil, fmt.Errorf("prompt contains no user messages")
+		}
+		if len(messages) == 0 {
+			return nil, nil
+		}
+	}
+
+	transport, d, err := c.session("send a prompt")
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the turn before sending so no reply can arrive ahead of it,
+	// and keep turns in the order their prompts reach the CLI
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	var sub *Subscription
+	if results > 0 {
+		sub = d.addTurn(ctx, results, track)
+	}
+	err = transport.SendRequest(messages, map[string]interface{}{
+		"session_id": sessionID,
+	})
+	if err != nil {
+		if results > 0 {
+			d.dropTurn(err)
+		}
+		return nil, err
+	}
+	return sub, nil
+}
+
+// promptMessages converts a prompt into the messages to send
+func promptMessages(prompt interface{}, sessionID string) ([]MessageData, error) {
 	var messages []MessageData
 
 	switch p := prompt.(type) {
@@ -172,24 +407,50 @@ func (c *Client) Query(ctx context.Context, prompt interface{}, sessionID string
 			messages = append(messages, msgData)
 		}
 	default:
-		return fmt.Errorf("unsupported prompt type: %T", prompt)
+		return nil, fmt.Errorf("unsupported prompt type: %T", prompt)
 	}
 
-	if len(messages) > 0 {
-		return c.transport.SendRequest(messages, map[string]interface{}{
-			"session_id": sessionID,
-		})
-	}
-
-	return nil
+	return messages, nil
 }
 
 // Interrupt sends an interrupt signal (only works with streaming mode)
 func (c *Client) Interrupt() error {
-	if !c.connected {
-		return NewCLIConnectionError("Not connected. Call Connect() first.")
+	transport, _, err := c.session("interrupt")
+	if err != nil {
+		return err
+	}
+	return transport.Interrupt()
+}
+
+// SetPermissionMode changes the permission mode for the rest of the session
+func (c *Client) SetPermissionMode(ctx context.Context, mode PermissionMode) error {
+	transport, _, err := c.session("set permission mode")
+	if err != nil {
+		return err
 	}
-	return c.transport.Interrupt()
+	_, err = transport.SendControlRequest(ctx, map[string]interface{}{
+		"subtype": "set_permission_mode",
+		"mode":    string(mode),
+	})
+	return err
+}
+
+// SetModel switches the model used for subsequent turns. A nil model
+// reverts to the CLI's default.
+func (c *Client) SetModel(ctx context.Context, model *string) error {
+	transport, _, err := c.session("set model")
+	if err != nil {
+		return err
+	}
+	request := map[string]interface{}{
+		"subtype": "set_model",
+		"model":   nil,
+	}
+	if model != nil {
+		request["model"] = *model
+	}
+	_, err = transport.SendControlRequest(ctx, request)
+	return err
 }
 
 // ReceiveResponse receives messages from Claude until and including a ResultMessage
@@ -197,41 +458,103 @@ func (c *Client) Interrupt() error {
 // This iterator yields all messages in sequence and automatically terminates
 // after yielding a ResultMessage (which indicates the response is complete).
 // It's a convenience method over ReceiveMessages() for single-response workflows.
+// Messages after the ResultMessage are left for the next receiver.
 func (c *Client) ReceiveResponse(ctx context.Context) (<-chan Message, error) {
-	allMsgs, err := c.ReceiveMessages(ctx)
+	sub, err := c.subscribe(ctx, true, true)
 	if err != nil {
 		return nil, err
 	}
+	return sub.Messages(), nil
+}
 
-	respChan := make(chan Message)
-	
-	go func() {
-		defer close(respChan)
-		
-		for msg := range allMsgs {
-			select {
-			case respChan <- msg:
-				if _, isResult := msg.(*ResultMessage); isResult {
-					return
-				}
-			case <-ctx.Done():
-				return
-			}
+// Wait blocks until the CLI process exits and returns the error that ended
+// the session, if any. A crashed CLI is reported as *ProcessError with its
+// exit code and stderr; a clean exit or Disconnect returns nil. Skipped
+// lines of output don't end the session; see StreamErr.
+func (c *Client) Wait(ctx context.Context) error {
+	c.mu.Lock()
+	transport := c.transport
+	if transport == nil {
+		var err error
+		switch c.state {
+		case ClientFailed:
+			err = c.err
+		case ClientClosed:
+		default:
+			err = c.notConnected("wait")
 		}
-	}()
+		c.mu.Unlock()
+		return err
+	}
+	c.mu.Unlock()
 
-	return respChan, nil
+	select {
+	case <-transport.Done():
+		return transport.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-// Disconnect disconnects from Claude
-func (c *Client) Disconnect() error {
+// StreamErr returns the first line of CLI output in the current or last
+// session that was skipped, as *JSONDecodeError if it was malformed or
+// *MessageTooLargeError if it was over MaxMessageSize, or nil if none was.
+// The session carries on past such lines.
+func (c *Client) StreamErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.transport != nil {
-		err := c.transport.Disconnect()
-		c.transport = nil
-		c.connected = false
+		return c.transport.StreamErr()
+	}
+	return c.streamErr
+}
+
+// Disconnect shuts the CLI down and waits for it to exit. It does nothing
+// on a Client that was never connected or is already closed, and waits for
+// a Disconnect already in progress. It returns a *ClientStateError while
+// Connect is in progress.
+func (c *Client) Disconnect() error {
+	c.mu.Lock()
+	switch c.state {
+	case ClientIdle:
+		c.mu.Unlock()
+		return nil
+	case ClientConnecting:
+		err := c.stateError("Disconnect")
+		c.mu.Unlock()
 		return err
+	case ClientDraining:
+		drained := c.drained
+		c.mu.Unlock()
+		<-drained
+		return nil
 	}
-	return nil
+
+	transport := c.transport
+	drained := make(chan struct{})
+	c.drained = drained
+	if c.state == ClientConnected {
+		c.setState(ClientDraining)
+	}
+	c.mu.Unlock()
+	c.notify()
+
+	var err error
+	var streamErr error
+	if transport != nil {
+		err = transport.Disconnect()
+		streamErr = transport.StreamErr()
+	}
+
+	c.mu.Lock()
+	c.streamErr = streamErr
+	c.transport = nil
+	c.dispatcher = nil
+	c.setState(ClientClosed)
+	close(drained)
+	c.mu.Unlock()
+	c.notify()
+	return err
 }
 
 // Close is an alias for Disconnect
@@ -239,65 +562,15 @@ func (c *Client) Close() error {
 	return c.Disconnect()
 }
 
-// Helper functions to convert between map and MessageData
-
-func messageDataToMap(data MessageData) map[string]interface{} {
-	result := make(map[string]interface{})
-	
-	result["type"] = data.Type
-	
-	if data.Message != nil {
-		result["message"] = data.Message
-	}
-	if data.ParentToolUseID != nil {
-		result["parent_tool_use_id"] = *data.ParentToolUseID
-	}
-	if data.SessionID != "" {
-		result["session_id"] = data.SessionID
-	}
-	if data.Content != nil {
-		result["content"] = data.Content
-	}
-	if data.Model != "" {
-		result["model"] = data.Model
-	}
-	if data.Subtype != "" {
-		result["subtype"] = data.Subtype
-	}
-	if data.Data != nil {
-		result["data"] = data.Data
-	}
-	if data.DurationMS > 0 {
-		result["duration_ms"] = data.DurationMS
-	}
-	if data.DurationAPIMS > 0 {
-		result["duration_api_ms"] = data.DurationAPIMS
-	}
-	result["is_error"] = data.IsError
-	if data.NumTurns > 0 {
-		result["num_turns"] = data.NumTurns
-	}
-	if data.TotalCostUSD != nil {
-		result["total_cost_usd"] = *data.TotalCostUSD
-	}
-	if data.Usage != nil {
-		result["usage"] = data.Usage
-	}
-	if data.Result != nil {
-		result["result"] = *data.Result
-	}
-	
-	return result
-}
-
+// mapToMessageData converts a user-supplied message map for SendRequest
 func mapToMessageData(m map[string]interface{}) MessageData {
 	data := MessageData{}
 	
 	if v, ok := m["type"].(string); ok {
 		data.Type = v
 	}
-	if v, ok := m["message"]; ok {
-		data.Message = v.(map[string]interface{})
+	if v, ok := m["message"].(map[string]interface{}); ok {
+		data.Message = v
 	}
 	if v, ok := m["parent_tool_use_id"].(string); ok {
 		data.ParentToolUseID = &v
@@ -338,6 +611,12 @@ func mapToMessageData(m map[string]interface{}) MessageData {
 	if v, ok := m["result"].(string); ok {
 		data.Result = &v
 	}
+	if v, ok := m["uuid"].(string); ok {
+		data.UUID = v
+	}
+	if v, ok := m["event"].(map[string]interface{}); ok {
+		data.Event = v
+	}
 	
 	return data
 }
\ No newline at end of file
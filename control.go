@@ -0,0 +1,266 @@
+package claudesdk
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultControlRequestTimeout bounds how long SendControlRequest waits for
+// the CLI to answer when the caller's context has no deadline
+const DefaultControlRequestTimeout = 60 * time.Second
+
+// ControlRequestHandler answers a control_request sent by the CLI.
+//
+// The request map is the "request" object of the control_request, including
+// its "subtype". The returned map is sent back as the "response" object of a
+// successful control_response; a non-nil error is sent back as an error
+// response. The context is cancelled if the CLI cancels the request or the
+// transport shuts down.
+type ControlRequestHandler func(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error)
+
+// controlResponse is the payload of a control_response line
+type controlResponse struct {
+	Subtype   string                 `json:"subtype"`
+	RequestID string                 `json:"request_id"`
+	Response  map[string]interface{} `json:"response,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+// controlResponseMessage is the envelope of a control_response line
+type controlResponseMessage struct {
+	Type     string          `json:"type"`
+	Response controlResponse `json:"response"`
+}
+
+// controlRequestMessage is the envelope of a control_request line
+type controlRequestMessage struct {
+	Type      string                 `json:"type"`
+	RequestID string                 `json:"request_id"`
+	Request   map[string]interface{} `json:"request"`
+}
+
+// controlCancelRequestMessage is the envelope of a control_cancel_request line
+type controlCancelRequestMessage struct {
+	Type      string `json:"type"`
+	RequestID string `json:"request_id"`
+}
+
+// controlProtocol implements the bidirectional control channel that runs
+// alongside the regular stream-json messages. Outgoing requests are
+// correlated with their responses by request ID, and incoming requests are
+// dispatched to handlers registered by subtype.
+type controlProtocol struct {
+	write func(v interface{}) error
+	done  <-chan struct{}
+
+	mu       sync.Mutex
+	counter  int
+	pending  map[string]chan controlResponse
+	handlers map[string]ControlRequestHandler
+	inflight map[string]context.CancelFunc
+}
+
+func newControlProtocol(write func(v interface{}) error, done <-chan struct{}) *controlProtocol {
+	return &controlProtocol{
+		write:    write,
+		done:     done,
+		pending:  make(map[string]chan controlResponse),
+		handlers: make(map[string]ControlRequestHandler),
+		inflight: make(map[string]context.CancelFunc),
+	}
+}
+
+// register installs the handler for incoming requests of the given subtype
+func (c *controlProtocol) register(subtype string, handler ControlRequestHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if handler == nil {
+		delete(c.handlers, subtype)
+		return
+	}
+	c.handlers[subtype] = handler
+}
+
+// hasHandlers reports whether any incoming request handlers are registered
+func (c *controlProtocol) hasHandlers() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.handlers) > 0
+}
+
+// send writes a control_request and blocks until the matching
+// control_response arrives, the context ends or the CLI exits
+func (c *controlProtocol) send(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultControlRequestTimeout)
+		defer cancel()
+	}
+
+	respChan := make(chan controlResponse, 1)
+
+	c.mu.Lock()
+	c.counter++
+	requestID := fmt.Sprintf("req_%d_%s", c.counter, randomHex(4))
+	c.pending[requestID] = respChan
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, requestID)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(map[string]interface{}{
+		"type":       "control_request",
+		"request_id": requestID,
+		"request":    request,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to send control request: %w", err)
+	}
+
+	select {
+	case resp := <-respChan:
+		if resp.Subtype == "error" {
+			return nil, fmt.Errorf("control request %v failed: %s", request["subtype"], resp.Error)
+		}
+		return resp.Response, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("control request %v: %w", request["subtype"], ctx.Err())
+	case <-c.done:
+		return nil, fmt.Errorf("CLI exited before responding to control request %v", request["subtype"])
+	}
+}
+
+// handleResponse delivers a control_response line to its waiting request.
+// Responses for requests that already timed out are dropped.
+func (c *controlProtocol) handleResponse(raw []byte) {
+	var msg controlResponseMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	respChan, ok := c.pending[msg.Response.RequestID]
+	c.mu.Unlock()
+
+	if ok {
+		select {
+		case respChan <- msg.Response:
+		default:
+		}
+	}
+}
+
+// handleRequest dispatches a control_request line from the CLI to the
+// registered handler and writes back its response
+func (c *controlProtocol) handleRequest(raw []byte) {
+	var msg controlRequestMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return
+	}
+
+	subtype, _ := msg.Request["subtype"].(string)
+
+	c.mu.Lock()
+	handler, ok := c.handlers[subtype]
+	ctx, cancel := context.WithCancel(context.Background())
+	c.inflight[msg.RequestID] = cancel
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.inflight, msg.RequestID)
+		c.mu.Unlock()
+		cancel()
+	}()
+
+	// Tie the handler's lifetime to the transport
+	go func() {
+		select {
+		case <-c.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var response map[string]interface{}
+	var err error
+	if !ok {
+		err = fmt.Errorf("unsupported control request subtype: %s", subtype)
+	} else {
+		response, err = c.invoke(ctx, handler, msg.Request)
+	}
+
+	if ctx.Err() != nil {
+		// Cancelled by the CLI or shutting down; nobody is waiting for an answer
+		return
+	}
+
+	if err != nil {
+		c.write(map[string]interface{}{
+			"type": "control_response",
+			"response": map[string]interface{}{
+				"subtype":    "error",
+				"request_id": msg.RequestID,
+				"error":      err.Error(),
+			},
+		})
+		return
+	}
+
+	if response == nil {
+		response = map[string]interface{}{}
+	}
+	c.write(map[string]interface{}{
+		"type": "control_response",
+		"response": map[string]interface{}{
+			"subtype":    "success",
+			"request_id": msg.RequestID,
+			"response":   response,
+		},
+	})
+}
+
+// invoke runs a handler, turning a panic into an error response
+func (c *controlProtocol) invoke(ctx context.Context, handler ControlRequestHandler, request map[string]interface{}) (response map[string]interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("control request handler panicked: %v", r)
+		}
+	}()
+
+	return handler(ctx, request)
+}
+
+// handleCancel cancels an in-flight incoming request
+func (c *controlProtocol) handleCancel(raw []byte) {
+	var msg controlCancelRequestMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	cancel, ok := c.inflight[msg.RequestID]
+	c.mu.Unlock()
+
+	if ok {
+		cancel()
+	}
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "0"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,157 @@
+package claudesdk
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendControlRequest(t *testing.T) {
+	t.Run("Responses matched by request ID", func(t *testing.T) {
+		transport, cli := newTestTransport(t, nil)
+
+		type result struct {
+			resp map[string]interface{}
+			err  error
+		}
+		first := make(chan result, 1)
+		second := make(chan result, 1)
+
+		go func() {
+			resp, err := transport.SendControlRequest(context.Background(), map[string]interface{}{"subtype": "first"})
+			first <- result{resp, err}
+		}()
+		req1 := cli.readLine(t)
+
+		go func() {
+			resp, err := transport.SendControlRequest(context.Background(), map[string]interface{}{"subtype": "second"})
+			second <- result{resp, err}
+		}()
+		req2 := cli.readLine(t)
+
+		assert.NotEqual(t, req1["request_id"], req2["request_id"])
+
+		// Answer out of order
+		for _, req := range []map[string]interface{}{req2, req1} {
+			cli.send(t, map[string]interface{}{
+				"type": "control_response",
+				"response": map[string]interface{}{
+					"subtype":    "success",
+					"request_id": req["request_id"],
+					"response": map[string]interface{}{
+						"echo": req["request"].(map[string]interface{})["subtype"],
+					},
+				},
+			})
+		}
+
+		r1 := <-first
+		require.NoError(t, r1.err)
+		assert.Equal(t, "first", r1.resp["echo"])
+
+		r2 := <-second
+		require.NoError(t, r2.err)
+		assert.Equal(t, "second", r2.resp["echo"])
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		transport, cli := newTestTransport(t, nil)
+		go cli.readLine(t)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := transport.SendControlRequest(ctx, map[string]interface{}{"subtype": "slow"})
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestIncomingControlRequests(t *testing.T) {
+	t.Run("Dispatched to handler", func(t *testing.T) {
+		transport, cli := newTestTransport(t, nil)
+		transport.RegisterControlHandler("echo", func(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error) {
+			return map[string]interface{}{"value": request["value"]}, nil
+		})
+
+		cli.send(t, map[string]interface{}{
+			"type":       "control_request",
+			"request_id": "cli_1",
+			"request": map[string]interface{}{
+				"subtype": "echo",
+				"value":   "ping",
+			},
+		})
+
+		resp := cli.readLine(t)
+		assert.Equal(t, "control_response", resp["type"])
+		body := resp["response"].(map[string]interface{})
+		assert.Equal(t, "success", body["subtype"])
+		assert.Equal(t, "cli_1", body["request_id"])
+		assert.Equal(t, "ping", body["response"].(map[string]interface{})["value"])
+	})
+
+	t.Run("Handler error", func(t *testing.T) {
+		transport, cli := newTestTransport(t, nil)
+		transport.RegisterControlHandler("fail", func(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error) {
+			return nil, fmt.Errorf("boom")
+		})
+
+		cli.send(t, map[string]interface{}{
+			"type":       "control_request",
+			"request_id": "cli_2",
+			"request":    map[string]interface{}{"subtype": "fail"},
+		})
+
+		body := cli.readLine(t)["response"].(map[string]interface{})
+		assert.Equal(t, "error", body["subtype"])
+		assert.Equal(t, "boom", body["error"])
+	})
+
+	t.Run("Unsupported subtype", func(t *testing.T) {
+		_, cli := newTestTransport(t, nil)
+
+		cli.send(t, map[string]interface{}{
+			"type":       "control_request",
+			"request_id": "cli_3",
+			"request":    map[string]interface{}{"subtype": "mystery"},
+		})
+
+		body := cli.readLine(t)["response"].(map[string]interface{})
+		assert.Equal(t, "error", body["subtype"])
+		assert.Contains(t, body["error"], "unsupported control request subtype")
+	})
+
+	t.Run("Cancelled by CLI", func(t *testing.T) {
+		transport, cli := newTestTransport(t, nil)
+
+		cancelled := make(chan struct{})
+		transport.RegisterControlHandler("wait", func(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error) {
+			<-ctx.Done()
+			close(cancelled)
+			return nil, ctx.Err()
+		})
+
+		cli.send(t, map[string]interface{}{
+			"type":       "control_request",
+			"request_id": "cli_4",
+			"request":    map[string]interface{}{"subtype": "wait"},
+		})
+		// The request is dispatched asynchronously; keep cancelling until it lands
+		for {
+			cli.send(t, map[string]interface{}{
+				"type":       "control_cancel_request",
+				"request_id": "cli_4",
+			})
+			select {
+			case <-cancelled:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package claudesdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxMessageSize is the largest message accepted from the CLI unless
+// MaxMessageSize is set
+const DefaultMaxMessageSize = 32 * 1024 * 1024
+
+// errBudgetExhausted stops the JSON decoder once a message has grown past
+// the size limit
+var errBudgetExhausted = errors.New("message size limit reached")
+
+// messageDecoder reads the stream of JSON messages written by the CLI. Its
+// cost is linear in the input, messages may span lines, and after a
+// malformed or oversized message it resumes at the next line.
+type messageDecoder struct {
+	src     *budgetReader
+	dec     *json.Decoder
+	maxSize int // <= 0 means unlimited
+}
+
+// budgetReader serves any unread bytes left over from a discarded decoder
+// before reading from r, and stops after budget bytes when budget >= 0
+type budgetReader struct {
+	r      io.Reader
+	prefix []byte
+	budget int
+}
+
+func (b *budgetReader) Read(p []byte) (int, error) {
+	if b.budget == 0 {
+		return 0, errBudgetExhausted
+	}
+	if b.budget > 0 && len(p) > b.budget {
+		p = p[:b.budget]
+	}
+
+	var n int
+	var err error
+	if len(b.prefix) > 0 {
+		n = copy(p, b.prefix)
+		b.prefix = b.prefix[n:]
+	} else {
+		n, err = b.r.Read(p)
+	}
+
+	if b.budget > 0 {
+		b.budget -= n
+	}
+	return n, err
+}
+
+// newMessageDecoder creates a decoder reading from r
+func newMessageDecoder(r io.Reader, maxSize int) *messageDecoder {
+	d := &messageDecoder{
+		src:     &budgetReader{r: r, budget: -1},
+		maxSize: maxSize,
+	}
+	d.dec = json.NewDecoder(d.src)
+	return d
+}
+
+// next returns the next message. A malformed message is reported as
+// *JSONDecodeError and an oversized one as *MessageTooLargeError; both are
+// skipped, so decoding can continue. io.EOF and read errors are final.
+func (d *messageDecoder) next() (json.RawMessage, error) {
+	d.src.budget = -1
+	if d.maxSize > 0 {
+		d.src.budget = d.maxSize
+	}
+
+	var raw json.RawMessage
+	err := d.dec.Decode(&raw)
+
+	var syntaxErr *json.SyntaxError
+	switch {
+	case err == nil:
+		if d.maxSize > 0 && len(raw) > d.maxSize {
+			return nil, d.tooLarge()
+		}
+		return raw, nil
+
+	case errors.Is(err, errBudgetExhausted):
+		d.skipLine()
+		return nil, d.tooLarge()
+
+	case errors.As(err, &syntaxErr):
+		snippet := d.snippet()
+		d.skipLine()
+		return nil, NewJSONDecodeError(fmt.Sprintf("failed to decode CLI output: %.200s", snippet), err)
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		snippet := d.snippet()
+		d.skipLine()
+		return nil, NewJSONDecodeError(fmt.Sprintf("CLI output ended mid-message: %.200s", snippet), err)
+
+	default:
+		return nil, err
+	}
+}
+
+// snippet returns the start of the input the decoder failed on
+func (d *messageDecoder) snippet() string {
+	data, _ := io.ReadAll(io.LimitReader(d.dec.Buffered(), 200))
+	return string(bytes.TrimSpace(data))
+}
+
+// skipLine discards input up to and including the next newline and starts
+// a fresh decoder after it. Bytes the old decoder buffered but did not
+// consume are kept.
+func (d *messageDecoder) skipLine() {
+	pending, _ := io.ReadAll(d.dec.Buffered())
+	pending = append(pending, d.src.prefix...)
+	// Whitespace before the bad message may hold the previous line's newline
+	pending = bytes.TrimLeft(pending, " \t\r\n")
+	d.src.prefix = nil
+	d.src.budget = -1
+
+	var readErr error
+	for {
+		if i := bytes.IndexByte(pending, '\n'); i >= 0 {
+			d.src.prefix = pending[i+1:]
+			break
+		}
+		if readErr != nil {
+			break
+		}
+		buf := make([]byte, 32*1024)
+		n, err := d.src.r.Read(buf)
+		pending, readErr = buf[:n], err
+	}
+
+	d.dec = json.NewDecoder(d.src)
+}
+
+func (d *messageDecoder) tooLarge() *MessageTooLargeError {
+	return &MessageTooLargeError{
+		CLIError: CLIError{Message: fmt.Sprintf("CLI message exceeded the maximum size of %d bytes", d.maxSize)},
+		Limit:    d.maxSize,
+	}
+}
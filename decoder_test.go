@@ -0,0 +1,119 @@
+package claudesdk
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeAll runs the decoder to the end, returning messages and
+// recoverable errors in order
+func decodeAll(t *testing.T, input string, maxSize int) ([]string, []error) {
+	t.Helper()
+
+	decoder := newMessageDecoder(strings.NewReader(input), maxSize)
+	var messages []string
+	var errs []error
+	for {
+		raw, err := decoder.next()
+		if err == io.EOF {
+			return messages, errs
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		messages = append(messages, string(raw))
+	}
+}
+
+func TestMessageDecoder(t *testing.T) {
+	t.Run("One message per line", func(t *testing.T) {
+		messages, errs := decodeAll(t, "{\"a\":1}\n\n{\"b\":2}\n", 0)
+		assert.Equal(t, []string{`{"a":1}`, `{"b":2}`}, messages)
+		assert.Empty(t, errs)
+	})
+
+	t.Run("Message split across lines", func(t *testing.T) {
+		messages, errs := decodeAll(t, "{\"type\":\"system\",\n\"subtype\":\"init\"}\n{\"b\":2}", 0)
+		assert.Equal(t, []string{"{\"type\":\"system\",\n\"subtype\":\"init\"}", `{"b":2}`}, messages)
+		assert.Empty(t, errs)
+	})
+
+	t.Run("Malformed line is skipped", func(t *testing.T) {
+		messages, errs := decodeAll(t, "not json at all\n{\"a\":1}\n{\"b\": nope}\n{\"c\":3}\n", 0)
+		assert.Equal(t, []string{`{"a":1}`, `{"c":3}`}, messages)
+		require.Len(t, errs, 2)
+		var decodeErr *JSONDecodeError
+		assert.True(t, errors.As(errs[0], &decodeErr))
+		assert.Contains(t, errs[0].Error(), "not json at all")
+	})
+
+	t.Run("Truncated output", func(t *testing.T) {
+		messages, errs := decodeAll(t, "{\"a\":1}\n{\"b\":", 0)
+		assert.Equal(t, []string{`{"a":1}`}, messages)
+		require.Len(t, errs, 1)
+		var decodeErr *JSONDecodeError
+		assert.True(t, errors.As(errs[0], &decodeErr))
+	})
+
+	t.Run("Oversized message is skipped", func(t *testing.T) {
+		big := `{"text":"` + strings.Repeat("x", 100_000) + `"}`
+		slightlyBig := `{"text":"` + strings.Repeat("x", 100) + `"}`
+		messages, errs := decodeAll(t, big+"\n{\"a\":1}\n"+slightlyBig+"\n{\"b\":2}\n", 64)
+		assert.Equal(t, []string{`{"a":1}`, `{"b":2}`}, messages)
+		require.Len(t, errs, 2)
+		for _, err := range errs {
+			var tooLarge *MessageTooLargeError
+			require.True(t, errors.As(err, &tooLarge))
+			assert.Equal(t, 64, tooLarge.Limit)
+		}
+	})
+
+	t.Run("Unlimited", func(t *testing.T) {
+		big := `{"text":"` + strings.Repeat("x", 5*1024*1024) + `"}`
+		messages, errs := decodeAll(t, big+"\n{\"a\":1}\n", -1)
+		require.Len(t, messages, 2)
+		assert.Len(t, messages[0], len(big))
+		assert.Empty(t, errs)
+	})
+}
+
+func TestTransportLargeMessage(t *testing.T) {
+	cli := writeFakeCLIScript(t, `printf '{"type":"user","message":{"role":"user","content":"%s"}}\n' "$(head -c 3000000 /dev/zero | tr '\0' a)"
+echo '{"type":"system","subtype":"init"}'
+`)
+
+	t.Run("Default limit", func(t *testing.T) {
+		transport, err := NewSubprocessCLITransport("hello", nil, cli, true)
+		require.NoError(t, err)
+		require.NoError(t, transport.Connect())
+		defer transport.Disconnect()
+
+		messages := collect(t, transport)
+		require.Len(t, messages, 2)
+		msg, err := ParseMessageFromJSON(messages[0].Raw)
+		require.NoError(t, err)
+		assert.Len(t, msg.(*UserMessage).Content, 3000000)
+		assert.NoError(t, transport.Err())
+	})
+
+	t.Run("Limit exceeded", func(t *testing.T) {
+		transport, err := NewSubprocessCLITransport("hello", &ClaudeCodeOptions{MaxMessageSize: 1024 * 1024}, cli, true)
+		require.NoError(t, err)
+		require.NoError(t, transport.Connect())
+		defer transport.Disconnect()
+
+		messages := collect(t, transport)
+		require.Len(t, messages, 1)
+		assert.Equal(t, "system", messages[0].Type)
+
+		assert.NoError(t, transport.Err())
+		var tooLarge *MessageTooLargeError
+		assert.True(t, errors.As(transport.StreamErr(), &tooLarge))
+	})
+}
@@ -0,0 +1,495 @@
+package claudesdk
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DefaultMessageBufferSize is how many messages a subscription queues, or a
+// Client holds, unless MessageBufferSize is set
+const DefaultMessageBufferSize = 4096
+
+// Subscription is one consumer's view of a Client's messages. It receives
+// every message that arrives while it is subscribed, in order, buffered
+// independently of other subscriptions.
+type Subscription struct {
+	d         *dispatcher
+	ctx       context.Context
+	claims    bool               // a receiver: takes held messages and stops them being held
+	stopAfter bool               // end after delivering a ResultMessage
+	match     func(Message) bool // messages to deliver; nil for all
+	until     func(Message) bool // ends the subscription cleanly; nil for never
+	out       chan Message
+	notify    chan struct{} // wakes the pump; capacity 1
+	stop      chan struct{} // closed by Unsubscribe
+	pumpDone  chan struct{}
+	stopOnce  sync.Once
+	turn      bool // receives the messages of one prompt sent with Send
+	detached  bool // turn abandoned; guarded by d.mu
+
+	mu         sync.Mutex
+	queue      []Message
+	ended      bool           // no more messages will be queued
+	err        error          // why the subscription ended
+	result     *ResultMessage // the ResultMessage that ended it, if any
+	releaseCtx func() bool
+}
+
+// Messages returns the channel of messages. It is closed when the session
+// ends, after Unsubscribe, or when the context passed to Subscribe is done.
+func (s *Subscription) Messages() <-chan Message {
+	return s.out
+}
+
+// Err returns why the subscription ended once Messages is closed: the
+// session's error, the context's error, or nil after a clean end or
+// Unsubscribe.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Unsubscribe detaches the subscription and closes Messages. A receiver's
+// undelivered messages are handed back to the Client, so the next receiver
+// gets them if no other is attached. It is safe to call more than once.
+func (s *Subscription) Unsubscribe() {
+	s.stopOnce.Do(func() {
+		s.release()
+		close(s.stop)
+		<-s.pumpDone
+		s.d.remove(s)
+	})
+}
+
+// push queues a message for delivery. It reports false if the subscription
+// has ended, including when the message would overflow its queue.
+func (s *Subscription) push(msg Message) bool {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return false
+	}
+	if limit := s.d.limit; limit > 0 && len(s.queue) >= limit {
+		s.ended = true
+		s.err = &BufferOverflowError{
+			CLIError: CLIError{Message: fmt.Sprintf("subscriber fell more than %d messages behind", limit)},
+			Limit:    limit,
+		}
+		s.mu.Unlock()
+		s.wake()
+		return false
+	}
+	s.queue = append(s.queue, msg)
+	s.mu.Unlock()
+	s.wake()
+	return true
+}
+
+// end marks that no more messages will be queued, unless it has already
+// ended
+func (s *Subscription) end(err error) {
+	s.mu.Lock()
+	if !s.ended {
+		s.ended = true
+		s.err = err
+	}
+	s.mu.Unlock()
+	s.wake()
+}
+
+// complete marks that the subscription ended with result, unless it has
+// already ended
+func (s *Subscription) complete(result *ResultMessage) {
+	s.mu.Lock()
+	if !s.ended {
+		s.ended = true
+		s.result = result
+	}
+	s.mu.Unlock()
+	s.wake()
+}
+
+// release stops watching the context
+func (s *Subscription) release() {
+	s.mu.Lock()
+	release := s.releaseCtx
+	s.mu.Unlock()
+	if release != nil {
+		release()
+	}
+}
+
+// cancel ends the subscription because its context is done
+func (s *Subscription) cancel() {
+	s.mu.Lock()
+	if !s.ended {
+		s.err = s.ctx.Err()
+	}
+	s.mu.Unlock()
+	s.Unsubscribe()
+}
+
+func (s *Subscription) wake() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pump delivers queued messages to out until the queue is drained after
+// the end of the stream, or until Unsubscribe
+func (s *Subscription) pump() {
+	defer close(s.pumpDone)
+	defer close(s.out)
+
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			ended := s.ended
+			s.mu.Unlock()
+			if ended {
+				s.release()
+				return
+			}
+			select {
+			case <-s.notify:
+				continue
+			case <-s.stop:
+				return
+			}
+		}
+		msg := s.queue[0]
+		s.queue[0] = nil
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		select {
+		case s.out <- msg:
+		case <-s.stop:
+			// Not delivered; put it back for the next subscriber
+			s.mu.Lock()
+			s.queue = append([]Message{msg}, s.queue...)
+			s.mu.Unlock()
+			return
+		}
+	}
+}
+
+// dispatcher is the single reader of a Client's transport. Each message is
+// parsed once and fanned out to every subscription. Messages that no
+// receiver takes are held for the next one; observers see them but don't
+// take them.
+//
+// The CLI answers prompts one at a time, each answer ending with a
+// ResultMessage, so the dispatcher also keeps the prompts it has sent in
+// order and attributes each message to the oldest unanswered one.
+type dispatcher struct {
+	mu       sync.Mutex
+	subs     map[*Subscription]struct{}
+	claimers int // receivers among subs
+	pending  []Message
+	turns    []turn
+	ended    bool
+	err      error
+	limit    int // per-queue message limit; 0 for none
+}
+
+// turn is a prompt awaiting its answer
+type turn struct {
+	sub     *Subscription // nil for prompts sent with Query, whose messages aren't attributed
+	results int           // ResultMessages still expected
+}
+
+// newDispatcher starts reading messages from transport
+func newDispatcher(transport Transport, options *ClaudeCodeOptions) (*dispatcher, error) {
+	dataChan, err := transport.ReceiveMessages()
+	if err != nil {
+		return nil, err
+	}
+
+	limit := options.MessageBufferSize
+	if limit == 0 {
+		limit = DefaultMessageBufferSize
+	} else if limit < 0 {
+		limit = 0
+	}
+
+	d := &dispatcher{
+		subs:  make(map[*Subscription]struct{}),
+		limit: limit,
+	}
+	go d.run(transport, dataChan, options)
+	return d, nil
+}
+
+func (d *dispatcher) run(transport Transport, dataChan <-chan MessageData, options *ClaudeCodeOptions) {
+	for data := range dataChan {
+		msg, err := decodeMessage(data, options)
+		if err != nil {
+			// Strict mode ends the stream for every subscriber, but keep
+			// reading so the CLI and control requests aren't blocked
+			d.finish(err)
+			for range dataChan {
+			}
+			return
+		}
+		if msg != nil {
+			d.publish(msg)
+		}
+	}
+	d.finish(transport.Err())
+}
+
+func (d *dispatcher) publish(msg Message) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.ended {
+		return
+	}
+
+	result, isResult := msg.(*ResultMessage)
+	attributed := false
+	if len(d.turns) > 0 {
+		t := &d.turns[0]
+		if t.sub != nil {
+			// A turn's messages are its own, even once it is abandoned or
+			// has overflowed
+			attributed = true
+			if !t.sub.detached {
+				t.sub.push(msg)
+			}
+		}
+		if isResult {
+			t.results--
+			if t.results == 0 {
+				if t.sub != nil {
+					t.sub.complete(result)
+				}
+				d.turns = d.turns[1:]
+			}
+		}
+	}
+
+	claimed := false
+	for s := range d.subs {
+		if s.until != nil && s.until(msg) {
+			d.detach(s)
+			s.end(nil)
+			continue
+		}
+		if s.match != nil && !s.match(msg) {
+			continue
+		}
+		if !s.push(msg) {
+			// Overflowed; a receiver's place is taken by the held messages
+			d.detach(s)
+			continue
+		}
+		if s.claims {
+			claimed = true
+		}
+		if isResult && s.stopAfter {
+			// Later messages go to the remaining subscribers, or are held
+			d.detach(s)
+			s.complete(result)
+		}
+	}
+
+	if !claimed && !attributed {
+		d.hold(msg)
+	}
+}
+
+// hold keeps messages for the next receiver, dropping the oldest beyond
+// the limit
+func (d *dispatcher) hold(msgs ...Message) {
+	d.pending = append(d.pending, msgs...)
+	if d.limit > 0 && len(d.pending) > d.limit {
+		drop := len(d.pending) - d.limit
+		clear(d.pending[:drop])
+		d.pending = d.pending[drop:]
+	}
+}
+
+// detach stops delivering to s
+func (d *dispatcher) detach(s *Subscription) {
+	if _, ok := d.subs[s]; !ok {
+		return
+	}
+	delete(d.subs, s)
+	if s.claims {
+		d.claimers--
+	}
+}
+
+// finish ends every subscription once the stream is over
+func (d *dispatcher) finish(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.ended {
+		return
+	}
+	d.ended = true
+	d.err = err
+	for s := range d.subs {
+		s.end(err)
+	}
+	for _, t := range d.turns {
+		if t.sub != nil {
+			t.sub.end(err)
+		}
+	}
+	d.turns = nil
+}
+
+// subscribe attaches a new subscription. A receiver (claim) takes the held
+// messages and, while attached, stops further ones being held; an observer
+// only sees what arrives. With stopAfterResult it ends after delivering the
+// next ResultMessage.
+func (d *dispatcher) subscribe(ctx context.Context, claim, stopAfterResult bool) *Subscription {
+	s := d.newSubscription(ctx, stopAfterResult)
+	s.claims = claim
+	return d.attach(s)
+}
+
+// observe attaches an observer that receives only the messages match
+// accepts, and ends without error at the first message until accepts
+func (d *dispatcher) observe(ctx context.Context, match, until func(Message) bool) *Subscription {
+	s := d.newSubscription(ctx, false)
+	s.match = match
+	s.until = until
+	return d.attach(s)
+}
+
+// attach registers s and starts delivery
+func (d *dispatcher) attach(s *Subscription) *Subscription {
+	if err := s.ctx.Err(); err != nil {
+		s.ended = true
+		s.err = err
+		go s.pump()
+		return s
+	}
+
+	d.mu.Lock()
+	if s.claims && d.claimers == 0 {
+		// The first receiver takes over the held messages
+		for i, msg := range d.pending {
+			s.queue = append(s.queue, msg)
+			if _, isResult := msg.(*ResultMessage); isResult && s.stopAfter {
+				d.pending = d.pending[i+1:]
+				s.ended = true
+				break
+			}
+		}
+		if !s.ended {
+			d.pending = nil
+		}
+	}
+	if !s.ended {
+		if d.ended {
+			s.ended = true
+			s.err = d.err
+		} else {
+			d.subs[s] = struct{}{}
+			if s.claims {
+				d.claimers++
+			}
+		}
+	}
+	d.mu.Unlock()
+
+	s.start()
+	return s
+}
+
+func (d *dispatcher) newSubscription(ctx context.Context, stopAfterResult bool) *Subscription {
+	return &Subscription{
+		d:         d,
+		ctx:       ctx,
+		stopAfter: stopAfterResult,
+		out:       make(chan Message),
+		notify:    make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		pumpDone:  make(chan struct{}),
+	}
+}
+
+// start begins delivery and watching the context
+func (s *Subscription) start() {
+	go s.pump()
+	release := context.AfterFunc(s.ctx, s.cancel)
+	s.mu.Lock()
+	s.releaseCtx = release
+	s.mu.Unlock()
+}
+
+// addTurn records a prompt about to be sent that expects results
+// ResultMessages. With track it returns a subscription that receives the
+// prompt's messages and ends with its last ResultMessage.
+func (d *dispatcher) addTurn(ctx context.Context, results int, track bool) *Subscription {
+	var s *Subscription
+	if track {
+		s = d.newSubscription(ctx, true)
+		s.turn = true
+	}
+
+	d.mu.Lock()
+	if d.ended {
+		if s != nil {
+			s.ended = true
+			s.err = d.err
+		}
+	} else {
+		d.turns = append(d.turns, turn{sub: s, results: results})
+	}
+	d.mu.Unlock()
+
+	if s != nil {
+		s.start()
+	}
+	return s
+}
+
+// dropTurn forgets the most recent turn after its prompt failed to send
+func (d *dispatcher) dropTurn(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if n := len(d.turns); n > 0 {
+		if s := d.turns[n-1].sub; s != nil {
+			s.end(err)
+		}
+		d.turns = d.turns[:n-1]
+	}
+}
+
+// remove detaches an unsubscribed subscription. If it was the last
+// receiver, its undelivered messages are held for the next one.
+func (d *dispatcher) remove(s *Subscription) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if s.turn {
+		// Keep the turn in line so its remaining messages aren't
+		// attributed to the next one, but discard them
+		s.detached = true
+		s.mu.Lock()
+		s.queue = nil
+		s.mu.Unlock()
+		return
+	}
+
+	d.detach(s)
+	if s.claims && d.claimers == 0 {
+		s.mu.Lock()
+		held := d.pending
+		d.pending = s.queue
+		d.hold(held...)
+		s.queue = nil
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,248 @@
+package claudesdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connectedClient returns a connected Client reading from transport
+func connectedClient(t *testing.T, transport *SubprocessCLITransport) *Client {
+	t.Helper()
+
+	d, err := newDispatcher(transport, transport.options)
+	require.NoError(t, err)
+	return &Client{options: transport.options, transport: transport, dispatcher: d, state: ClientConnected}
+}
+
+// systemMessage returns a system message with the given subtype
+func systemMessage(subtype string) map[string]interface{} {
+	return map[string]interface{}{"type": "system", "subtype": subtype}
+}
+
+// resultMessage returns a successful result message
+func resultMessage(sessionID string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":            "result",
+		"subtype":         "success",
+		"duration_ms":     10,
+		"duration_api_ms": 5,
+		"is_error":        false,
+		"num_turns":       1,
+		"session_id":      sessionID,
+	}
+}
+
+// receive reads n messages from ch, failing after a timeout
+func receive(t *testing.T, ch <-chan Message, n int) []Message {
+	t.Helper()
+
+	var messages []Message
+	for len(messages) < n {
+		select {
+		case msg, ok := <-ch:
+			require.True(t, ok, "channel closed after %d messages", len(messages))
+			messages = append(messages, msg)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out after %d messages", len(messages))
+		}
+	}
+	return messages
+}
+
+func subtypes(messages []Message) []string {
+	var result []string
+	for _, msg := range messages {
+		switch m := msg.(type) {
+		case *SystemMessage:
+			result = append(result, m.Subtype)
+		case *ResultMessage:
+			result = append(result, "result")
+		}
+	}
+	return result
+}
+
+func TestSubscriptions(t *testing.T) {
+	t.Run("Every subscriber sees every message", func(t *testing.T) {
+		transport, cli := newTestTransport(t, nil)
+		client := connectedClient(t, transport)
+
+		ui, err := client.Subscribe(context.Background())
+		require.NoError(t, err)
+		logger, err := client.Subscribe(context.Background())
+		require.NoError(t, err)
+
+		for i := 0; i < 50; i++ {
+			cli.send(t, systemMessage(fmt.Sprint(i)))
+		}
+
+		// The logger reads only after the UI has everything, so a
+		// subscriber that falls behind holds up nobody
+		uiMessages := receive(t, ui.Messages(), 50)
+		loggerMessages := receive(t, logger.Messages(), 50)
+		assert.Equal(t, subtypes(uiMessages), subtypes(loggerMessages))
+		assert.Equal(t, "49", uiMessages[49].(*SystemMessage).Subtype)
+
+		ui.Unsubscribe()
+		ui.Unsubscribe()
+		_, open := <-ui.Messages()
+		assert.False(t, open)
+
+		// The remaining subscriber is unaffected
+		cli.send(t, systemMessage("after"))
+		assert.Equal(t, []string{"after"}, subtypes(receive(t, logger.Messages(), 1)))
+	})
+
+	t.Run("Responses in turn lose nothing", func(t *testing.T) {
+		transport, cli := newTestTransport(t, nil)
+		client := connectedClient(t, transport)
+
+		// Both turns are already buffered before anyone receives
+		cli.send(t, systemMessage("turn1"))
+		cli.send(t, resultMessage("s1"))
+		cli.send(t, systemMessage("turn2"))
+		cli.send(t, resultMessage("s1"))
+
+		for _, turn := range []string{"turn1", "turn2"} {
+			response, err := client.ReceiveResponse(context.Background())
+			require.NoError(t, err)
+
+			var got []Message
+			for msg := range response {
+				got = append(got, msg)
+			}
+			assert.Equal(t, []string{turn, "result"}, subtypes(got))
+		}
+	})
+
+	t.Run("Unsubscribe hands back undelivered messages", func(t *testing.T) {
+		transport, cli := newTestTransport(t, nil)
+		client := connectedClient(t, transport)
+
+		cli.send(t, systemMessage("a"))
+		cli.send(t, systemMessage("b"))
+		cli.send(t, systemMessage("c"))
+		for msg, err := range client.ReceiveMessagesIter(context.Background()) {
+			require.NoError(t, err)
+			assert.Equal(t, []string{"a"}, subtypes([]Message{msg}))
+			break
+		}
+
+		// b and c may be queued on the first receiver, or not yet read;
+		// either way the next receiver gets them in order
+		second, err := client.ReceiveMessages(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b", "c"}, subtypes(receive(t, second, 2)))
+	})
+
+	t.Run("A logger doesn't use up a response", func(t *testing.T) {
+		transport, cli := newTestTransport(t, nil)
+		client := connectedClient(t, transport)
+
+		logger, err := client.Subscribe(context.Background())
+		require.NoError(t, err)
+		cli.send(t, systemMessage("status"))
+		cli.send(t, resultMessage("s1"))
+		assert.Equal(t, []string{"status", "result"}, subtypes(receive(t, logger.Messages(), 2)))
+
+		response, err := client.ReceiveResponse(context.Background())
+		require.NoError(t, err)
+		var got []Message
+		for msg := range response {
+			got = append(got, msg)
+		}
+		assert.Equal(t, []string{"status", "result"}, subtypes(got))
+
+		// A new observer doesn't see what came before it
+		late, err := client.Subscribe(context.Background())
+		require.NoError(t, err)
+		cli.send(t, systemMessage("next"))
+		assert.Equal(t, []string{"next"}, subtypes(receive(t, late.Messages(), 1)))
+	})
+
+	t.Run("A subscriber that falls behind is ended", func(t *testing.T) {
+		transport, cli := newTestTransport(t, &ClaudeCodeOptions{MessageBufferSize: 2})
+		client := connectedClient(t, transport)
+
+		slow, err := client.Subscribe(context.Background())
+		require.NoError(t, err)
+		for i := 0; i < 10; i++ {
+			cli.send(t, systemMessage(fmt.Sprint(i)))
+		}
+		require.Eventually(t, func() bool {
+			slow.mu.Lock()
+			defer slow.mu.Unlock()
+			return slow.ended
+		}, 2*time.Second, 5*time.Millisecond)
+
+		// What was queued is delivered in order, then the overflow. The
+		// pump may already hold one message beyond the queue.
+		var got []Message
+		for msg := range slow.Messages() {
+			got = append(got, msg)
+		}
+		assert.Contains(t, [][]string{{"0", "1"}, {"0", "1", "2"}}, subtypes(got))
+		var overflow *BufferOverflowError
+		require.True(t, errors.As(slow.Err(), &overflow))
+		assert.Equal(t, 2, overflow.Limit)
+	})
+
+	t.Run("Held messages drop the oldest", func(t *testing.T) {
+		transport, cli := newTestTransport(t, &ClaudeCodeOptions{MessageBufferSize: 3})
+		client := connectedClient(t, transport)
+
+		for _, subtype := range []string{"a", "b", "c", "d", "e"} {
+			cli.send(t, systemMessage(subtype))
+		}
+		require.Eventually(t, func() bool {
+			client.dispatcher.mu.Lock()
+			defer client.dispatcher.mu.Unlock()
+			held := client.dispatcher.pending
+			return len(held) > 0 && held[len(held)-1].(*SystemMessage).Subtype == "e"
+		}, 2*time.Second, 5*time.Millisecond)
+
+		messages, err := client.ReceiveMessages(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"c", "d", "e"}, subtypes(receive(t, messages, 3)))
+	})
+
+	t.Run("Context cancellation", func(t *testing.T) {
+		transport, _ := newTestTransport(t, nil)
+		client := connectedClient(t, transport)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		sub, err := client.Subscribe(ctx)
+		require.NoError(t, err)
+		cancel()
+
+		for range sub.Messages() {
+		}
+		assert.ErrorIs(t, sub.Err(), context.Canceled)
+	})
+
+	t.Run("Session errors reach every subscriber", func(t *testing.T) {
+		transport, cli := newTestTransport(t, &ClaudeCodeOptions{ParseErrorPolicy: ParseErrorStrict})
+		client := connectedClient(t, transport)
+
+		var subs []*Subscription
+		for i := 0; i < 2; i++ {
+			sub, err := client.Subscribe(context.Background())
+			require.NoError(t, err)
+			subs = append(subs, sub)
+		}
+		cli.send(t, map[string]interface{}{"type": "brand_new"})
+
+		for _, sub := range subs {
+			for range sub.Messages() {
+			}
+			var parseErr *MessageParseError
+			assert.True(t, errors.As(sub.Err(), &parseErr))
+		}
+	})
+}
@@ -61,6 +61,31 @@ type JSONDecodeError struct {
 	CLIError
 }
 
+// MessageTooLargeError indicates the CLI sent a message larger than
+// MaxMessageSize. The message is skipped.
+type MessageTooLargeError struct {
+	CLIError
+	Limit int
+}
+
+// BufferOverflowError indicates a Subscription or Turn fell more than
+// Limit messages behind its reader and was ended. Messages queued before
+// the overflow are still delivered.
+type BufferOverflowError struct {
+	CLIError
+	Limit int
+}
+
+// ClientStateError indicates an operation that the Client's current state
+// does not allow, such as Query before Connect or Connect while another
+// Connect is in progress. When the Client is not connected it wraps a
+// *CLIConnectionError.
+type ClientStateError struct {
+	CLIError
+	Op    string
+	State ClientState
+}
+
 // NewCLINotFoundError creates a new CLINotFoundError
 func NewCLINotFoundError(message string) *CLINotFoundError {
 	return &CLINotFoundError{
@@ -97,4 +122,71 @@ func NewJSONDecodeError(message string, cause error) *JSONDecodeError {
 	return &JSONDecodeError{
 		CLIError: CLIError{Message: message, Cause: cause},
 	}
-}
\ No newline at end of file
+}
+
+// ResultError indicates a query ended with an error result
+type ResultError struct {
+	CLIError
+	Subtype   string
+	SessionID string
+	NumTurns  int
+	Result    *ResultMessage
+}
+
+func (e *ResultError) Error() string {
+	msg := fmt.Sprintf("Query failed (%s) after %d turns in session %s", e.Subtype, e.NumTurns, e.SessionID)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// MaxTurnsError indicates a query stopped because it reached MaxTurns.
+// The session can be continued by passing SessionID as Resume.
+type MaxTurnsError struct {
+	ResultError
+}
+
+func (e *MaxTurnsError) Error() string {
+	return fmt.Sprintf("Reached maximum number of turns (%d) in session %s", e.NumTurns, e.SessionID)
+}
+
+// Unwrap lets errors.As match *ResultError for any failed result
+func (e *MaxTurnsError) Unwrap() error {
+	return &e.ResultError
+}
+
+// ExecutionError indicates a query failed while executing
+type ExecutionError struct {
+	ResultError
+}
+
+// Unwrap lets errors.As match *ResultError for any failed result
+func (e *ExecutionError) Unwrap() error {
+	return &e.ResultError
+}
+
+// MaxBudgetError indicates a query stopped because it exceeded its cost budget
+type MaxBudgetError struct {
+	ResultError
+}
+
+// Unwrap lets errors.As match *ResultError for any failed result
+func (e *MaxBudgetError) Unwrap() error {
+	return &e.ResultError
+}
+
+// newResultError builds the base error for a failed result
+func newResultError(m *ResultMessage) ResultError {
+	message := ""
+	if m.Result != nil {
+		message = *m.Result
+	}
+	return ResultError{
+		CLIError:  CLIError{Message: message},
+		Subtype:   m.Subtype,
+		SessionID: m.SessionID,
+		NumTurns:  m.NumTurns,
+		Result:    m,
+	}
+}
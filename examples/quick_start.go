@@ -73,8 +73,53 @@ func withToolsExample() {
 	fmt.Println()
 }
 
+func iteratorExample() {
+	fmt.Println("=== Iterator Example ===")
+	
+	ctx := context.Background()
+	
+	for msg, err := range sdk.QueryIter(ctx, "Name three primary colors.", nil) {
+		if err != nil {
+			fmt.Printf("Query failed: %v\n", err)
+			break
+		}
+		if assistantMsg, ok := msg.(*sdk.AssistantMessage); ok {
+			for _, block := range assistantMsg.Content {
+				if textBlock, ok := block.(*sdk.TextBlock); ok {
+					fmt.Printf("Claude: %s\n", textBlock.Text)
+				}
+			}
+		}
+	}
+	fmt.Println()
+}
+
+func askExample() {
+	fmt.Println("=== Ask Example ===")
+
+	ctx := context.Background()
+
+	options := &sdk.ClaudeCodeOptions{
+		AllowedTools: []string{"Read"},
+	}
+
+	resp, err := sdk.Ask(ctx, "What does go.mod in this directory declare?", options)
+	if err != nil {
+		fmt.Printf("Ask failed: %v\n", err)
+		return
+	}
+	for _, call := range resp.ToolCalls() {
+		fmt.Printf("Tool: %s\n", call.Use.Name)
+	}
+	fmt.Printf("Claude: %s\n", resp.Text())
+	fmt.Printf("Cost: $%.4f in %s\n", resp.CostUSD(), resp.Duration())
+	fmt.Println()
+}
+
 func main() {
 	basicExample()
 	withOptionsExample()
 	withToolsExample()
+	iteratorExample()
+	askExample()
 }
\ No newline at end of file
@@ -123,36 +123,83 @@ func exampleConcurrentResponses() {
 	}
 	defer client.Disconnect()
 
-	// Start receiving messages in background
-	msgChan, err := client.ReceiveMessages(ctx)
+	// Send all questions up front; each turn receives only its own answer
+	questions := []string{
+		"What is 2 + 2?",
+		"What is the square root of 144?",
+		"What is 10% of 80?",
+	}
+
+	var turns []*sdk.Turn
+	for _, question := range questions {
+		turn, err := client.Send(ctx, question, "default")
+		if err != nil {
+			log.Fatal(err)
+		}
+		turns = append(turns, turn)
+	}
+
+	for i, turn := range turns {
+		fmt.Printf("\nUser: %s\n", questions[i])
+		for msg := range turn.Messages() {
+			displayMessage(msg)
+		}
+		if err := turn.Err(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	fmt.Println()
+}
+
+func exampleInterrupt() {
+	fmt.Println("=== Interrupt Example ===")
+
+	ctx := context.Background()
+	client := sdk.NewClient(nil)
+
+	if err := client.Connect(ctx, nil); err != nil {
+		log.Fatal(err)
+	}
+	defer client.Disconnect()
+
+	fmt.Println("User: Count from 1 to 100 slowly, one number per line")
+	if err := client.Query(ctx, "Count from 1 to 100 slowly, one number per line", "default"); err != nil {
+		log.Fatal(err)
+	}
+
+	responseChan, err := client.ReceiveResponse(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// Background goroutine to display messages
+	// Stop the turn after a couple of seconds
 	go func() {
-		for msg := range msgChan {
-			displayMessage(msg)
+		time.Sleep(2 * time.Second)
+		fmt.Println("\n[Sending interrupt...]")
+		if err := client.Interrupt(); err != nil {
+			fmt.Printf("Interrupt failed: %v\n", err)
 		}
 	}()
 
-	// Send multiple messages with delays
-	questions := []string{
-		"What is 2 + 2?",
-		"What is the square root of 144?",
-		"What is 10% of 80?",
+	for msg := range responseChan {
+		displayMessage(msg)
 	}
 
-	for _, question := range questions {
-		fmt.Printf("\nUser: %s\n", question)
-		if err := client.Query(ctx, question, "default"); err != nil {
-			log.Fatal(err)
-		}
-		time.Sleep(3 * time.Second)
+	// The session is still usable after an interrupt
+	fmt.Println("\nUser: Just say 'Hello!'")
+	if err := client.Query(ctx, "Just say 'Hello!'", "default"); err != nil {
+		log.Fatal(err)
 	}
 
-	// Give time for final responses
-	time.Sleep(2 * time.Second)
+	responseChan, err = client.ReceiveResponse(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for msg := range responseChan {
+		displayMessage(msg)
+	}
 
 	fmt.Println()
 }
@@ -277,6 +324,7 @@ func main() {
 		fmt.Println("  basic_streaming")
 		fmt.Println("  multi_turn_conversation")
 		fmt.Println("  concurrent_responses")
+		fmt.Println("  interrupt")
 		fmt.Println("  with_options")
 		fmt.Println("  manual_message_handling")
 		os.Exit(0)
@@ -286,6 +334,7 @@ func main() {
 		"basic_streaming":         exampleBasicStreaming,
 		"multi_turn_conversation": exampleMultiTurnConversation,
 		"concurrent_responses":    exampleConcurrentResponses,
+		"interrupt":               exampleInterrupt,
 		"with_options":            exampleWithOptions,
 		"manual_message_handling": exampleManualMessageHandling,
 	}
@@ -0,0 +1,213 @@
+package claudesdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// HookEvent identifies a point in the CLI's lifecycle where hooks run
+type HookEvent string
+
+const (
+	HookEventPreToolUse       HookEvent = "PreToolUse"
+	HookEventPostToolUse      HookEvent = "PostToolUse"
+	HookEventUserPromptSubmit HookEvent = "UserPromptSubmit"
+	HookEventStop             HookEvent = "Stop"
+	HookEventSubagentStop     HookEvent = "SubagentStop"
+	HookEventPreCompact       HookEvent = "PreCompact"
+)
+
+// HookInput is the JSON input the CLI passes to a hook. Fields that do not
+// apply to the event are left empty; Raw holds the complete input.
+type HookInput struct {
+	HookEventName  HookEvent              `json:"hook_event_name"`
+	SessionID      string                 `json:"session_id"`
+	TranscriptPath string                 `json:"transcript_path"`
+	CWD            string                 `json:"cwd"`
+	PermissionMode string                 `json:"permission_mode,omitempty"`
+	ToolName       string                 `json:"tool_name,omitempty"`
+	ToolInput      map[string]interface{} `json:"tool_input,omitempty"`
+	ToolResponse   interface{}            `json:"tool_response,omitempty"`
+	Prompt         string                 `json:"prompt,omitempty"`
+	StopHookActive bool                   `json:"stop_hook_active,omitempty"`
+	Trigger        string                 `json:"trigger,omitempty"`
+	Raw            map[string]interface{} `json:"-"`
+}
+
+// HookSpecificOutput carries event-specific decisions
+type HookSpecificOutput struct {
+	HookEventName HookEvent `json:"hookEventName"`
+	// PermissionDecision is "allow", "deny" or "ask" (PreToolUse only)
+	PermissionDecision       string                 `json:"permissionDecision,omitempty"`
+	PermissionDecisionReason string                 `json:"permissionDecisionReason,omitempty"`
+	UpdatedInput             map[string]interface{} `json:"updatedInput,omitempty"`
+	// AdditionalContext is added to the conversation (PostToolUse, UserPromptSubmit)
+	AdditionalContext string `json:"additionalContext,omitempty"`
+}
+
+// HookOutput is a hook's decision, sent back to the CLI as JSON. The zero
+// value lets the CLI continue normally.
+type HookOutput struct {
+	Continue           *bool               `json:"continue,omitempty"`
+	SuppressOutput     bool                `json:"suppressOutput,omitempty"`
+	StopReason         string              `json:"stopReason,omitempty"`
+	Decision           string              `json:"decision,omitempty"` // "block"
+	Reason             string              `json:"reason,omitempty"`
+	SystemMessage      string              `json:"systemMessage,omitempty"`
+	HookSpecificOutput *HookSpecificOutput `json:"hookSpecificOutput,omitempty"`
+}
+
+// HookCallback is invoked when a hook event fires. toolUseID is set for
+// tool-related events.
+type HookCallback func(ctx context.Context, input HookInput, toolUseID *string) (HookOutput, error)
+
+// HookMatcher selects which tool calls trigger its hooks
+type HookMatcher struct {
+	// Matcher is a tool name pattern such as "Bash" or "Write|Edit".
+	// Empty matches every tool (and is the only option for non-tool events).
+	Matcher string
+	Hooks   []HookCallback
+	// Timeout overrides the CLI's default hook timeout when non-zero
+	Timeout time.Duration
+}
+
+// HookBlock returns an output that blocks the action with a reason shown to Claude
+func HookBlock(reason string) HookOutput {
+	return HookOutput{Decision: "block", Reason: reason}
+}
+
+// HookAllow returns a PreToolUse output that approves the tool call without prompting
+func HookAllow(reason string) HookOutput {
+	return HookOutput{HookSpecificOutput: &HookSpecificOutput{
+		HookEventName:            HookEventPreToolUse,
+		PermissionDecision:       "allow",
+		PermissionDecisionReason: reason,
+	}}
+}
+
+// HookDeny returns a PreToolUse output that rejects the tool call
+func HookDeny(reason string) HookOutput {
+	return HookOutput{HookSpecificOutput: &HookSpecificOutput{
+		HookEventName:            HookEventPreToolUse,
+		PermissionDecision:       "deny",
+		PermissionDecisionReason: reason,
+	}}
+}
+
+// HookModifyInput returns a PreToolUse output that approves the tool call with a rewritten input
+func HookModifyInput(input map[string]interface{}) HookOutput {
+	return HookOutput{HookSpecificOutput: &HookSpecificOutput{
+		HookEventName:      HookEventPreToolUse,
+		PermissionDecision: "allow",
+		UpdatedInput:       input,
+	}}
+}
+
+// HookAddContext returns an output that adds context to the conversation
+func HookAddContext(event HookEvent, text string) HookOutput {
+	return HookOutput{HookSpecificOutput: &HookSpecificOutput{
+		HookEventName:     event,
+		AdditionalContext: text,
+	}}
+}
+
+// hookRegistry assigns callback IDs to the configured hooks
+type hookRegistry struct {
+	callbacks map[string]HookCallback
+	config    map[string]interface{}
+}
+
+// newHookRegistry builds the initialize request's hook configuration
+func newHookRegistry(hooks map[HookEvent][]HookMatcher) *hookRegistry {
+	r := &hookRegistry{
+		callbacks: make(map[string]HookCallback),
+		config:    make(map[string]interface{}),
+	}
+
+	for event, matchers := range hooks {
+		var eventConfig []map[string]interface{}
+		for _, matcher := range matchers {
+			var ids []string
+			for _, callback := range matcher.Hooks {
+				id := fmt.Sprintf("hook_%d", len(r.callbacks))
+				r.callbacks[id] = callback
+				ids = append(ids, id)
+			}
+
+			matcherConfig := map[string]interface{}{
+				"matcher":         nil,
+				"hookCallbackIds": ids,
+			}
+			if matcher.Matcher != "" {
+				matcherConfig["matcher"] = matcher.Matcher
+			}
+			if matcher.Timeout > 0 {
+				matcherConfig["timeout"] = matcher.Timeout.Seconds()
+			}
+			eventConfig = append(eventConfig, matcherConfig)
+		}
+		if len(eventConfig) > 0 {
+			r.config[string(event)] = eventConfig
+		}
+	}
+
+	return r
+}
+
+// handler answers "hook_callback" control requests
+func (r *hookRegistry) handler() ControlRequestHandler {
+	return func(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error) {
+		callbackID, _ := request["callback_id"].(string)
+		callback, ok := r.callbacks[callbackID]
+		if !ok {
+			return nil, fmt.Errorf("no hook callback found for ID: %s", callbackID)
+		}
+
+		rawInput, _ := request["input"].(map[string]interface{})
+		input, err := parseHookInput(rawInput)
+		if err != nil {
+			return nil, err
+		}
+
+		var toolUseID *string
+		if id, ok := request["tool_use_id"].(string); ok {
+			toolUseID = &id
+		}
+
+		output, err := callback(ctx, input, toolUseID)
+		if err != nil {
+			return nil, err
+		}
+
+		return toMap(output)
+	}
+}
+
+// parseHookInput decodes the hook input map into a HookInput
+func parseHookInput(raw map[string]interface{}) (HookInput, error) {
+	var input HookInput
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return input, fmt.Errorf("invalid hook input: %w", err)
+	}
+	if err := json.Unmarshal(data, &input); err != nil {
+		return input, fmt.Errorf("invalid hook input: %w", err)
+	}
+	input.Raw = raw
+	return input, nil
+}
+
+// toMap converts a JSON-serializable value into a generic map
+func toMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
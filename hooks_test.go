@@ -0,0 +1,122 @@
+package claudesdk
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks(t *testing.T) {
+	var seen []HookInput
+	options := &ClaudeCodeOptions{
+		Hooks: map[HookEvent][]HookMatcher{
+			HookEventPreToolUse: {
+				{
+					Matcher: "Bash",
+					Timeout: 30 * time.Second,
+					Hooks: []HookCallback{
+						func(ctx context.Context, input HookInput, toolUseID *string) (HookOutput, error) {
+							seen = append(seen, input)
+							if strings.Contains(input.ToolInput["command"].(string), "rm -rf") {
+								return HookDeny("dangerous command"), nil
+							}
+							return HookOutput{}, nil
+						},
+					},
+				},
+			},
+		},
+	}
+
+	transport, cli := newTestTransport(t, options)
+
+	t.Run("Initialize registers hooks", func(t *testing.T) {
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- transport.initialize(context.Background())
+		}()
+
+		req := cli.readLine(t)
+		request := req["request"].(map[string]interface{})
+		assert.Equal(t, "initialize", request["subtype"])
+
+		matchers := request["hooks"].(map[string]interface{})["PreToolUse"].([]interface{})
+		require.Len(t, matchers, 1)
+		matcher := matchers[0].(map[string]interface{})
+		assert.Equal(t, "Bash", matcher["matcher"])
+		assert.Equal(t, float64(30), matcher["timeout"])
+		assert.Equal(t, []interface{}{"hook_0"}, matcher["hookCallbackIds"])
+
+		cli.send(t, map[string]interface{}{
+			"type": "control_response",
+			"response": map[string]interface{}{
+				"subtype":    "success",
+				"request_id": req["request_id"],
+			},
+		})
+		assert.NoError(t, <-errChan)
+	})
+
+	hookCallback := func(t *testing.T, command string) map[string]interface{} {
+		t.Helper()
+		cli.send(t, map[string]interface{}{
+			"type":       "control_request",
+			"request_id": "hook_req",
+			"request": map[string]interface{}{
+				"subtype":     "hook_callback",
+				"callback_id": "hook_0",
+				"tool_use_id": "toolu_1",
+				"input": map[string]interface{}{
+					"hook_event_name": "PreToolUse",
+					"session_id":      "session-1",
+					"tool_name":       "Bash",
+					"tool_input":      map[string]interface{}{"command": command},
+				},
+			},
+		})
+		body := cli.readLine(t)["response"].(map[string]interface{})
+		require.Equal(t, "success", body["subtype"])
+		return body["response"].(map[string]interface{})
+	}
+
+	t.Run("Deny decision", func(t *testing.T) {
+		resp := hookCallback(t, "rm -rf /")
+		output := resp["hookSpecificOutput"].(map[string]interface{})
+		assert.Equal(t, "PreToolUse", output["hookEventName"])
+		assert.Equal(t, "deny", output["permissionDecision"])
+		assert.Equal(t, "dangerous command", output["permissionDecisionReason"])
+	})
+
+	t.Run("Continue", func(t *testing.T) {
+		resp := hookCallback(t, "ls")
+		assert.Empty(t, resp)
+	})
+
+	require.Len(t, seen, 2)
+	assert.Equal(t, HookEventPreToolUse, seen[0].HookEventName)
+	assert.Equal(t, "Bash", seen[0].ToolName)
+	assert.Equal(t, "session-1", seen[0].Raw["session_id"])
+}
+
+func TestHookOutputJSON(t *testing.T) {
+	stop := false
+	output, err := toMap(HookOutput{
+		Continue:      &stop,
+		StopReason:    "budget exhausted",
+		SystemMessage: "stopping",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, false, output["continue"])
+	assert.Equal(t, "budget exhausted", output["stopReason"])
+	assert.Equal(t, "stopping", output["systemMessage"])
+
+	output, err = toMap(HookAddContext(HookEventUserPromptSubmit, "user is on the billing team"))
+	require.NoError(t, err)
+	specific := output["hookSpecificOutput"].(map[string]interface{})
+	assert.Equal(t, "UserPromptSubmit", specific["hookEventName"])
+	assert.Equal(t, "user is on the billing team", specific["additionalContext"])
+}
@@ -0,0 +1,73 @@
+package claudesdk
+
+import (
+	"context"
+	"iter"
+)
+
+// QueryIter performs a one-shot interaction like Query, as a range-over-func
+// iterator. Each message is yielded with a nil error; if the query fails,
+// a final (nil, err) pair is yielded. Breaking out of the loop stops the
+// query and terminates the CLI subprocess.
+//
+// Example:
+//
+//	for msg, err := range QueryIter(ctx, "What is 2+2?", nil) {
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    fmt.Println(msg)
+//	}
+func QueryIter(ctx context.Context, prompt interface{}, options *ClaudeCodeOptions) iter.Seq2[Message, error] {
+	return func(yield func(Message, error) bool) {
+		stream := QueryStream(ctx, prompt, options)
+		defer stream.Close()
+
+		for msg := range stream.Messages() {
+			if !yield(msg, nil) {
+				return
+			}
+		}
+
+		if err := stream.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// ReceiveMessagesIter is the iterator form of ReceiveMessages. Breaking out
+// of the loop unsubscribes, leaves no goroutine behind, and hands messages
+// not yet yielded back to the Client for the next receiver. If the session
+// ends with an error, or ctx is cancelled, a final (nil, err) pair is
+// yielded.
+func (c *Client) ReceiveMessagesIter(ctx context.Context) iter.Seq2[Message, error] {
+	return c.receiveIter(ctx, false)
+}
+
+// ReceiveResponseIter is the iterator form of ReceiveResponse. It stops
+// after yielding a ResultMessage.
+func (c *Client) ReceiveResponseIter(ctx context.Context) iter.Seq2[Message, error] {
+	return c.receiveIter(ctx, true)
+}
+
+// receiveIter yields the messages of a receiver
+func (c *Client) receiveIter(ctx context.Context, stopAfterResult bool) iter.Seq2[Message, error] {
+	return func(yield func(Message, error) bool) {
+		sub, err := c.subscribe(ctx, true, stopAfterResult)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer sub.Unsubscribe()
+
+		for msg := range sub.Messages() {
+			if !yield(msg, nil) {
+				return
+			}
+		}
+
+		if err := sub.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package claudesdk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryIter(t *testing.T) {
+	t.Run("Yields messages", func(t *testing.T) {
+		useFakeCLI(t, `echo '{"type":"system","subtype":"init"}'
+echo '{"type":"result","subtype":"success","duration_ms":10,"duration_api_ms":5,"is_error":false,"num_turns":1,"session_id":"s1"}'
+`)
+
+		var messages []Message
+		for msg, err := range QueryIter(context.Background(), "hello", nil) {
+			require.NoError(t, err)
+			messages = append(messages, msg)
+		}
+		assert.Len(t, messages, 2)
+	})
+
+	t.Run("Yields terminal error", func(t *testing.T) {
+		useFakeCLI(t, `exit 2`)
+
+		var lastErr error
+		for _, err := range QueryIter(context.Background(), "hello", nil) {
+			lastErr = err
+		}
+		var processErr *ProcessError
+		require.True(t, errors.As(lastErr, &processErr))
+		assert.Equal(t, 2, processErr.ExitCode)
+	})
+
+	t.Run("Break terminates the CLI", func(t *testing.T) {
+		useFakeCLI(t, `echo '{"type":"system","subtype":"init"}'
+exec sleep 30
+`)
+
+		start := time.Now()
+		for msg, err := range QueryIter(context.Background(), "hello", nil) {
+			require.NoError(t, err)
+			assert.IsType(t, &InitMessage{}, msg)
+			break
+		}
+		assert.Less(t, time.Since(start), 10*time.Second)
+	})
+}
+
+func TestClientReceiveIter(t *testing.T) {
+	transport, cli := newTestTransport(t, nil)
+	client := connectedClient(t, transport)
+
+	go func() {
+		cli.send(t, map[string]interface{}{"type": "system", "subtype": "init"})
+		cli.send(t, map[string]interface{}{
+			"type":            "result",
+			"subtype":         "success",
+			"duration_ms":     10,
+			"duration_api_ms": 5,
+			"is_error":        false,
+			"num_turns":       1,
+			"session_id":      "s1",
+		})
+		cli.send(t, map[string]interface{}{"type": "system", "subtype": "next"})
+	}()
+
+	var response []Message
+	for msg, err := range client.ReceiveResponseIter(context.Background()) {
+		require.NoError(t, err)
+		response = append(response, msg)
+	}
+	require.Len(t, response, 2)
+	assert.IsType(t, &ResultMessage{}, response[1])
+
+	// Nothing was consumed past the result
+	for msg, err := range client.ReceiveMessagesIter(context.Background()) {
+		require.NoError(t, err)
+		assert.Equal(t, "next", msg.(*SystemMessage).Subtype)
+		break
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err := range client.ReceiveMessagesIter(ctx) {
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+}
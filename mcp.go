@@ -0,0 +1,236 @@
+package claudesdk
+
+import (
+	"context"
+	"fmt"
+)
+
+// mcpProtocolVersion is the MCP protocol revision spoken by SDK servers
+const mcpProtocolVersion = "2024-11-05"
+
+// JSON-RPC error codes used by SDK MCP servers
+const (
+	jsonRPCMethodNotFound = -32601
+	jsonRPCInvalidParams  = -32602
+)
+
+// MCPToolHandler implements an SDK MCP tool. Returning an error reports a
+// failed tool call to Claude with the error's message.
+type MCPToolHandler func(ctx context.Context, args map[string]interface{}) (*MCPToolResult, error)
+
+// SDKMCPTool is a tool served by an in-process SDK MCP server
+type SDKMCPTool struct {
+	Name        string
+	Description string
+	// InputSchema is the JSON Schema for the tool's arguments
+	InputSchema map[string]interface{}
+	Handler     MCPToolHandler
+}
+
+// MCPContent is a single content item of a tool result
+type MCPContent struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// MCPToolResult is the result of an SDK MCP tool call
+type MCPToolResult struct {
+	Content []MCPContent `json:"content"`
+	IsError bool         `json:"isError,omitempty"`
+}
+
+// TextResult returns a tool result holding a single text item
+func TextResult(text string) *MCPToolResult {
+	return &MCPToolResult{
+		Content: []MCPContent{{Type: "text", Text: text}},
+	}
+}
+
+// SDKMCPServer is an MCP server that runs inside the SDK process. The CLI
+// talks to it over the control protocol instead of spawning a subprocess.
+type SDKMCPServer struct {
+	name    string
+	version string
+	tools   []SDKMCPTool
+	byName  map[string]SDKMCPTool
+}
+
+// CreateSDKMCPServer creates an in-process MCP server config. Add it to
+// ClaudeCodeOptions.MCPServers; its tools are exposed to Claude as
+// mcp__<key>__<tool name>.
+//
+// Example:
+//
+//	add := SDKMCPTool{
+//	    Name:        "add",
+//	    Description: "Add two numbers",
+//	    InputSchema: map[string]interface{}{
+//	        "type": "object",
+//	        "properties": map[string]interface{}{
+//	            "a": map[string]interface{}{"type": "number"},
+//	            "b": map[string]interface{}{"type": "number"},
+//	        },
+//	        "required": []string{"a", "b"},
+//	    },
+//	    Handler: func(ctx context.Context, args map[string]interface{}) (*MCPToolResult, error) {
+//	        return TextResult(fmt.Sprint(args["a"].(float64) + args["b"].(float64))), nil
+//	    },
+//	}
+//	options.MCPServers["calc"] = CreateSDKMCPServer("calc", "1.0.0", add)
+func CreateSDKMCPServer(name, version string, tools ...SDKMCPTool) MCPSDKServerConfig {
+	server := &SDKMCPServer{
+		name:    name,
+		version: version,
+		tools:   tools,
+		byName:  make(map[string]SDKMCPTool, len(tools)),
+	}
+	for _, tool := range tools {
+		server.byName[tool.Name] = tool
+	}
+
+	return MCPSDKServerConfig{
+		Type:     MCPServerTypeSDK,
+		Name:     name,
+		Instance: server,
+	}
+}
+
+// handleMessage answers a JSON-RPC message sent to the server by the CLI
+func (s *SDKMCPServer) handleMessage(ctx context.Context, message map[string]interface{}) map[string]interface{} {
+	method, _ := message["method"].(string)
+	params, _ := message["params"].(map[string]interface{})
+
+	response := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      message["id"],
+	}
+
+	switch method {
+	case "initialize":
+		response["result"] = map[string]interface{}{
+			"protocolVersion": mcpProtocolVersion,
+			"capabilities": map[string]interface{}{
+				"tools": map[string]interface{}{},
+			},
+			"serverInfo": map[string]interface{}{
+				"name":    s.name,
+				"version": s.version,
+			},
+		}
+
+	case "notifications/initialized":
+		response["result"] = map[string]interface{}{}
+
+	case "tools/list":
+		tools := make([]map[string]interface{}, 0, len(s.tools))
+		for _, tool := range s.tools {
+			schema := tool.InputSchema
+			if schema == nil {
+				schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+			}
+			tools = append(tools, map[string]interface{}{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"inputSchema": schema,
+			})
+		}
+		response["result"] = map[string]interface{}{"tools": tools}
+
+	case "tools/call":
+		name, _ := params["name"].(string)
+		tool, ok := s.byName[name]
+		if !ok {
+			response["error"] = map[string]interface{}{
+				"code":    jsonRPCInvalidParams,
+				"message": fmt.Sprintf("Tool '%s' not found", name),
+			}
+			break
+		}
+		args, _ := params["arguments"].(map[string]interface{})
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		response["result"] = s.callTool(ctx, tool, args)
+
+	default:
+		response["error"] = map[string]interface{}{
+			"code":    jsonRPCMethodNotFound,
+			"message": fmt.Sprintf("Method '%s' not found", method),
+		}
+	}
+
+	return response
+}
+
+// callTool runs a tool handler, reporting errors and panics as failed tool results
+func (s *SDKMCPServer) callTool(ctx context.Context, tool SDKMCPTool, args map[string]interface{}) (result *MCPToolResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = &MCPToolResult{
+				Content: []MCPContent{{Type: "text", Text: fmt.Sprintf("tool %s panicked: %v", tool.Name, r)}},
+				IsError: true,
+			}
+		}
+	}()
+
+	result, err := tool.Handler(ctx, args)
+	if err != nil {
+		return &MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: err.Error()}},
+			IsError: true,
+		}
+	}
+	if result == nil {
+		return &MCPToolResult{Content: []MCPContent{}}
+	}
+	return result
+}
+
+// sdkMCPServers returns the in-process servers configured on the options, keyed by server name
+func sdkMCPServers(options *ClaudeCodeOptions) map[string]*SDKMCPServer {
+	servers := make(map[string]*SDKMCPServer)
+	for name, config := range options.MCPServers {
+		switch c := config.(type) {
+		case MCPSDKServerConfig:
+			if c.Instance != nil {
+				servers[name] = c.Instance
+			}
+		case *MCPSDKServerConfig:
+			if c != nil && c.Instance != nil {
+				servers[name] = c.Instance
+			}
+		}
+	}
+	return servers
+}
+
+// mcpMessageHandler routes "mcp_message" control requests to SDK MCP servers
+func mcpMessageHandler(servers map[string]*SDKMCPServer) ControlRequestHandler {
+	return func(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error) {
+		serverName, _ := request["server_name"].(string)
+		message, ok := request["message"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("mcp_message request missing 'message' field")
+		}
+
+		server, ok := servers[serverName]
+		if !ok {
+			return map[string]interface{}{
+				"mcp_response": map[string]interface{}{
+					"jsonrpc": "2.0",
+					"id":      message["id"],
+					"error": map[string]interface{}{
+						"code":    jsonRPCMethodNotFound,
+						"message": fmt.Sprintf("Server '%s' not found", serverName),
+					},
+				},
+			}, nil
+		}
+
+		return map[string]interface{}{
+			"mcp_response": server.handleMessage(ctx, message),
+		}, nil
+	}
+}
@@ -0,0 +1,125 @@
+package claudesdk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCalculatorServer() MCPSDKServerConfig {
+	return CreateSDKMCPServer("calculator", "1.0.0",
+		SDKMCPTool{
+			Name:        "add",
+			Description: "Add two numbers",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"a": map[string]interface{}{"type": "number"},
+					"b": map[string]interface{}{"type": "number"},
+				},
+			},
+			Handler: func(ctx context.Context, args map[string]interface{}) (*MCPToolResult, error) {
+				return TextResult(fmt.Sprint(args["a"].(float64) + args["b"].(float64))), nil
+			},
+		},
+		SDKMCPTool{
+			Name:        "divide",
+			Description: "Divide two numbers",
+			Handler: func(ctx context.Context, args map[string]interface{}) (*MCPToolResult, error) {
+				if args["b"].(float64) == 0 {
+					return nil, fmt.Errorf("division by zero")
+				}
+				return TextResult(fmt.Sprint(args["a"].(float64) / args["b"].(float64))), nil
+			},
+		},
+	)
+}
+
+func TestSDKMCPServer(t *testing.T) {
+	options := NewClaudeCodeOptions()
+	options.MCPServers["calc"] = newCalculatorServer()
+
+	_, cli := newTestTransport(t, options)
+
+	mcpRequest := func(t *testing.T, serverName string, message map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		cli.send(t, map[string]interface{}{
+			"type":       "control_request",
+			"request_id": "mcp_1",
+			"request": map[string]interface{}{
+				"subtype":     "mcp_message",
+				"server_name": serverName,
+				"message":     message,
+			},
+		})
+		body := cli.readLine(t)["response"].(map[string]interface{})
+		require.Equal(t, "success", body["subtype"])
+		return body["response"].(map[string]interface{})["mcp_response"].(map[string]interface{})
+	}
+
+	t.Run("Initialize", func(t *testing.T) {
+		resp := mcpRequest(t, "calc", map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "initialize"})
+		result := resp["result"].(map[string]interface{})
+		assert.Equal(t, "calculator", result["serverInfo"].(map[string]interface{})["name"])
+	})
+
+	t.Run("List tools", func(t *testing.T) {
+		resp := mcpRequest(t, "calc", map[string]interface{}{"jsonrpc": "2.0", "id": 2, "method": "tools/list"})
+		tools := resp["result"].(map[string]interface{})["tools"].([]interface{})
+		require.Len(t, tools, 2)
+		assert.Equal(t, "add", tools[0].(map[string]interface{})["name"])
+		assert.NotNil(t, tools[1].(map[string]interface{})["inputSchema"])
+	})
+
+	t.Run("Call tool", func(t *testing.T) {
+		resp := mcpRequest(t, "calc", map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      3,
+			"method":  "tools/call",
+			"params": map[string]interface{}{
+				"name":      "add",
+				"arguments": map[string]interface{}{"a": 2, "b": 3},
+			},
+		})
+		result := resp["result"].(map[string]interface{})
+		content := result["content"].([]interface{})
+		assert.Equal(t, "5", content[0].(map[string]interface{})["text"])
+		assert.Nil(t, result["isError"])
+	})
+
+	t.Run("Tool error", func(t *testing.T) {
+		resp := mcpRequest(t, "calc", map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      4,
+			"method":  "tools/call",
+			"params": map[string]interface{}{
+				"name":      "divide",
+				"arguments": map[string]interface{}{"a": 1, "b": 0},
+			},
+		})
+		result := resp["result"].(map[string]interface{})
+		assert.Equal(t, true, result["isError"])
+		assert.Equal(t, "division by zero", result["content"].([]interface{})[0].(map[string]interface{})["text"])
+	})
+
+	t.Run("Unknown server", func(t *testing.T) {
+		resp := mcpRequest(t, "missing", map[string]interface{}{"jsonrpc": "2.0", "id": 5, "method": "tools/list"})
+		assert.NotNil(t, resp["error"])
+	})
+}
+
+func TestSDKMCPServerConfig(t *testing.T) {
+	options := NewClaudeCodeOptions()
+	options.MCPServers["calc"] = newCalculatorServer()
+
+	transport, err := NewSubprocessCLITransport("hello", options, "claude", true)
+	require.NoError(t, err)
+
+	cmd := strings.Join(transport.buildCommand(), " ")
+	assert.Contains(t, cmd, `{"mcpServers":{"calc":{"type":"sdk","name":"calculator"}}}`)
+	assert.True(t, transport.isStreaming)
+}
@@ -2,234 +2,533 @@ package claudesdk
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 )
 
-// ParseMessage parses a message from CLI output into typed Message objects
+// ParseMessage parses a message that has already been decoded into a map.
+// ParseMessageFromJSON is cheaper when the original JSON is at hand.
 func ParseMessage(data map[string]interface{}) (Message, error) {
-	messageType, ok := data["type"].(string)
-	if !ok {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+	return ParseMessageFromJSON(raw)
+}
+
+// ParseMessageFromJSON parses a JSON message from the CLI into a typed
+// Message. The message type selects the Go type the JSON is decoded into,
+// with no intermediate map. The result's Raw field references jsonData and
+// keeps the fields this SDK doesn't model.
+func ParseMessageFromJSON(jsonData []byte) (Message, error) {
+	var envelope struct {
+		Type *string `json:"type"`
+	}
+	if err := json.Unmarshal(jsonData, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if envelope.Type == nil {
 		return nil, fmt.Errorf("message missing 'type' field")
 	}
+	return parseMessageJSON(*envelope.Type, jsonData)
+}
 
+// parseMessageJSON decodes jsonData into the Go type for messageType
+func parseMessageJSON(messageType string, jsonData []byte) (Message, error) {
 	switch messageType {
 	case "user":
-		return parseUserMessage(data)
+		return parseUserMessage(jsonData)
 	case "assistant":
-		return parseAssistantMessage(data)
+		return parseAssistantMessage(jsonData)
 	case "system":
-		return parseSystemMessage(data)
+		return parseSystemJSON(jsonData)
 	case "result":
-		return parseResultMessage(data)
+		return parseResultMessage(jsonData)
+	case "stream_event":
+		return parseStreamEvent(jsonData)
 	default:
 		return nil, fmt.Errorf("unknown message type: %s", messageType)
 	}
 }
 
-func parseUserMessage(data map[string]interface{}) (*UserMessage, error) {
-	messageData, ok := data["message"].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("missing 'message' field in user message")
+// errInvalidBlockList is returned when content that must be a list of
+// blocks is something else
+var errInvalidBlockList = errors.New("content is not a list of blocks")
+
+// blockList decodes a JSON array of content blocks
+type blockList []ContentBlock
+
+func (l *blockList) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || data[0] != '[' {
+		return errInvalidBlockList
 	}
 
-	content := messageData["content"]
-	
-	// Check if content is a list of blocks
-	if contentList, ok := content.([]interface{}); ok {
-		blocks := []ContentBlock{}
-		for _, item := range contentList {
-			block, err := parseContentBlock(item)
-			if err != nil {
-				return nil, err
-			}
-			blocks = append(blocks, block)
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+
+	blocks := make([]ContentBlock, 0, len(items))
+	for _, item := range items {
+		block, err := parseContentBlockJSON(item)
+		if err != nil {
+			return err
 		}
-		return &UserMessage{Content: blocks}, nil
+		blocks = append(blocks, block)
 	}
-	
-	// Otherwise it's a string
-	return &UserMessage{Content: content}, nil
+	*l = blocks
+	return nil
 }
 
-func parseAssistantMessage(data map[string]interface{}) (*AssistantMessage, error) {
-	messageData, ok := data["message"].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("missing 'message' field in assistant message")
-	}
+// userContent decodes user message content, which is either a string or a
+// list of blocks
+type userContent struct {
+	value interface{}
+}
 
-	model, ok := messageData["model"].(string)
-	if !ok {
-		return nil, fmt.Errorf("missing 'model' field in assistant message")
+func (c *userContent) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '[' {
+		var blocks blockList
+		if err := blocks.UnmarshalJSON(data); err != nil {
+			return err
+		}
+		c.value = []ContentBlock(blocks)
+		return nil
 	}
+	return json.Unmarshal(data, &c.value)
+}
 
-	contentData, ok := messageData["content"].([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("missing or invalid 'content' field in assistant message")
+func parseUserMessage(data []byte) (*UserMessage, error) {
+	var wire struct {
+		Message *struct {
+			Content userContent `json:"content"`
+		} `json:"message"`
+		SessionID       string  `json:"session_id"`
+		ParentToolUseID *string `json:"parent_tool_use_id"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return nil, fmt.Errorf("invalid user message: %w", err)
+	}
+	if wire.Message == nil {
+		return nil, fmt.Errorf("missing 'message' field in user message")
 	}
 
-	blocks := []ContentBlock{}
-	for _, item := range contentData {
-		block, err := parseContentBlock(item)
-		if err != nil {
-			return nil, err
+	return &UserMessage{
+		Content:         wire.Message.Content.value,
+		SessionID:       wire.SessionID,
+		ParentToolUseID: wire.ParentToolUseID,
+		Raw:             data,
+	}, nil
+}
+
+func parseAssistantMessage(data []byte) (*AssistantMessage, error) {
+	var wire struct {
+		Message *struct {
+			Model   *string    `json:"model"`
+			Content *blockList `json:"content"`
+			Usage   *Usage     `json:"usage"`
+		} `json:"message"`
+		SessionID       string  `json:"session_id"`
+		ParentToolUseID *string `json:"parent_tool_use_id"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		if errors.Is(err, errInvalidBlockList) {
+			return nil, fmt.Errorf("missing or invalid 'content' field in assistant message")
 		}
-		blocks = append(blocks, block)
+		return nil, fmt.Errorf("invalid assistant message: %w", err)
+	}
+	if wire.Message == nil {
+		return nil, fmt.Errorf("missing 'message' field in assistant message")
+	}
+	if wire.Message.Model == nil {
+		return nil, fmt.Errorf("missing 'model' field in assistant message")
+	}
+	if wire.Message.Content == nil {
+		return nil, fmt.Errorf("missing or invalid 'content' field in assistant message")
 	}
 
 	return &AssistantMessage{
-		Content: blocks,
-		Model:   model,
+		Content:         *wire.Message.Content,
+		Model:           *wire.Message.Model,
+		Usage:           wire.Message.Usage,
+		SessionID:       wire.SessionID,
+		ParentToolUseID: wire.ParentToolUseID,
+		Raw:             data,
 	}, nil
 }
 
+// parseContentBlock parses a content block that has already been decoded
+// into a map
 func parseContentBlock(item interface{}) (ContentBlock, error) {
-	blockData, ok := item.(map[string]interface{})
-	if !ok {
+	if _, ok := item.(map[string]interface{}); !ok {
 		return nil, fmt.Errorf("invalid content block format")
 	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content block format: %w", err)
+	}
+	return parseContentBlockJSON(data)
+}
 
-	blockType, ok := blockData["type"].(string)
-	if !ok {
+// parseContentBlockJSON decodes a content block into the type named by its
+// "type" field. Unknown types become an *UnknownBlock.
+func parseContentBlockJSON(data []byte) (ContentBlock, error) {
+	if len(data) == 0 || data[0] != '{' {
+		return nil, fmt.Errorf("invalid content block format")
+	}
+
+	var peek struct {
+		Type *string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &peek); err != nil {
+		return nil, fmt.Errorf("invalid content block: %w", err)
+	}
+	if peek.Type == nil {
 		return nil, fmt.Errorf("content block missing 'type' field")
 	}
+	blockType := *peek.Type
+
+	decode := func(v interface{}) error {
+		if err := json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("invalid %s block: %w", blockType, err)
+		}
+		return nil
+	}
 
 	switch blockType {
 	case "text":
-		text, ok := blockData["text"].(string)
-		if !ok {
+		var wire struct {
+			Text *string `json:"text"`
+		}
+		if err := decode(&wire); err != nil {
+			return nil, err
+		}
+		if wire.Text == nil {
 			return nil, fmt.Errorf("text block missing 'text' field")
 		}
-		return &TextBlock{Text: text}, nil
+		return &TextBlock{Text: *wire.Text}, nil
 
 	case "thinking":
-		thinking, ok := blockData["thinking"].(string)
-		if !ok {
-			return nil, fmt.Errorf("thinking block missing 'thinking' field")
+		var wire struct {
+			Thinking *string `json:"thinking"`
+			// The signature arrives last when streaming, so it may be absent
+			Signature string `json:"signature"`
 		}
-		signature, ok := blockData["signature"].(string)
-		if !ok {
-			return nil, fmt.Errorf("thinking block missing 'signature' field")
+		if err := decode(&wire); err != nil {
+			return nil, err
+		}
+		if wire.Thinking == nil {
+			return nil, fmt.Errorf("thinking block missing 'thinking' field")
 		}
 		return &ThinkingBlock{
-			Thinking:  thinking,
-			Signature: signature,
+			Thinking:  *wire.Thinking,
+			Signature: wire.Signature,
 		}, nil
 
 	case "tool_use":
-		id, ok := blockData["id"].(string)
-		if !ok {
+		var wire struct {
+			ID    *string                `json:"id"`
+			Name  *string                `json:"name"`
+			Input map[string]interface{} `json:"input"`
+		}
+		if err := decode(&wire); err != nil {
+			return nil, err
+		}
+		if wire.ID == nil {
 			return nil, fmt.Errorf("tool_use block missing 'id' field")
 		}
-		name, ok := blockData["name"].(string)
-		if !ok {
+		if wire.Name == nil {
 			return nil, fmt.Errorf("tool_use block missing 'name' field")
 		}
-		input, ok := blockData["input"].(map[string]interface{})
-		if !ok {
+		if wire.Input == nil {
 			return nil, fmt.Errorf("tool_use block missing 'input' field")
 		}
 		return &ToolUseBlock{
-			ID:    id,
-			Name:  name,
-			Input: input,
+			ID:    *wire.ID,
+			Name:  *wire.Name,
+			Input: wire.Input,
 		}, nil
 
 	case "tool_result":
-		toolUseID, ok := blockData["tool_use_id"].(string)
-		if !ok {
+		var wire struct {
+			ToolUseID *string     `json:"tool_use_id"`
+			Content   interface{} `json:"content"`
+			IsError   *bool       `json:"is_error"`
+		}
+		if err := decode(&wire); err != nil {
+			return nil, err
+		}
+		if wire.ToolUseID == nil {
 			return nil, fmt.Errorf("tool_result block missing 'tool_use_id' field")
 		}
-		
-		result := &ToolResultBlock{
-			ToolUseID: toolUseID,
+		return &ToolResultBlock{
+			ToolUseID: *wire.ToolUseID,
+			Content:   wire.Content,
+			IsError:   wire.IsError,
+		}, nil
+
+	case "image", "document":
+		var wire struct {
+			Source *BlockSource `json:"source"`
+			Title  string       `json:"title"`
 		}
-		
-		if content, exists := blockData["content"]; exists {
-			result.Content = content
+		if err := decode(&wire); err != nil {
+			return nil, err
 		}
-		
-		if isError, exists := blockData["is_error"]; exists {
-			if b, ok := isError.(bool); ok {
-				result.IsError = &b
-			}
+		if wire.Source == nil {
+			return nil, fmt.Errorf("%s block missing 'source' field", blockType)
+		}
+		if blockType == "image" {
+			return &ImageBlock{Source: *wire.Source}, nil
 		}
-		
-		return result, nil
+		return &DocumentBlock{Source: *wire.Source, Title: wire.Title}, nil
+
+	case "redacted_thinking":
+		var wire struct {
+			Data *string `json:"data"`
+		}
+		if err := decode(&wire); err != nil {
+			return nil, err
+		}
+		if wire.Data == nil {
+			return nil, fmt.Errorf("redacted_thinking block missing 'data' field")
+		}
+		return &RedactedThinkingBlock{Data: *wire.Data}, nil
+
+	case "server_tool_use":
+		var wire struct {
+			ID    *string                `json:"id"`
+			Name  *string                `json:"name"`
+			Input map[string]interface{} `json:"input"`
+		}
+		if err := decode(&wire); err != nil {
+			return nil, err
+		}
+		if wire.ID == nil {
+			return nil, fmt.Errorf("server_tool_use block missing 'id' field")
+		}
+		if wire.Name == nil {
+			return nil, fmt.Errorf("server_tool_use block missing 'name' field")
+		}
+		return &ServerToolUseBlock{
+			ID:    *wire.ID,
+			Name:  *wire.Name,
+			Input: wire.Input,
+		}, nil
+
+	case "web_search_tool_result":
+		var wire struct {
+			ToolUseID *string     `json:"tool_use_id"`
+			Content   interface{} `json:"content"`
+		}
+		if err := decode(&wire); err != nil {
+			return nil, err
+		}
+		if wire.ToolUseID == nil {
+			return nil, fmt.Errorf("web_search_tool_result block missing 'tool_use_id' field")
+		}
+		return &WebSearchToolResultBlock{
+			ToolUseID: *wire.ToolUseID,
+			Content:   wire.Content,
+		}, nil
 
 	default:
-		return nil, fmt.Errorf("unknown content block type: %s", blockType)
+		// Keep blocks we don't know about rather than failing the whole message
+		var blockData map[string]interface{}
+		if err := decode(&blockData); err != nil {
+			return nil, err
+		}
+		return &UnknownBlock{
+			Type: blockType,
+			Data: blockData,
+			Raw:  append(json.RawMessage(nil), data...),
+		}, nil
 	}
 }
 
-func parseSystemMessage(data map[string]interface{}) (*SystemMessage, error) {
-	subtype, ok := data["subtype"].(string)
-	if !ok {
-		return nil, fmt.Errorf("missing 'subtype' field in system message")
+// parseSystemJSON decodes a system message. System messages keep their
+// complete data as a map, so it is decoded once into one.
+func parseSystemJSON(data []byte) (Message, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("invalid system message: %w", err)
 	}
 
-	return &SystemMessage{
-		Subtype: subtype,
-		Data:    data,
-	}, nil
+	msg, err := parseSystemMessage(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	switch m := msg.(type) {
+	case *SystemMessage:
+		m.Raw = data
+	case *InitMessage:
+		m.Raw = data
+	case *CompactBoundaryMessage:
+		m.Raw = data
+	case *APIRetryMessage:
+		m.Raw = data
+	}
+	return msg, nil
 }
 
-func parseResultMessage(data map[string]interface{}) (*ResultMessage, error) {
+func parseSystemMessage(data map[string]interface{}) (Message, error) {
 	subtype, ok := data["subtype"].(string)
 	if !ok {
-		return nil, fmt.Errorf("missing 'subtype' field in result message")
+		return nil, fmt.Errorf("missing 'subtype' field in system message")
 	}
 
-	durationMS, ok := getInt(data, "duration_ms")
-	if !ok {
-		return nil, fmt.Errorf("missing 'duration_ms' field in result message")
+	base := SystemMessage{
+		Subtype: subtype,
+		Data:    data,
 	}
+	sessionID, _ := data["session_id"].(string)
+
+	switch subtype {
+	case "init":
+		msg := &InitMessage{
+			SystemMessage: base,
+			SessionID:     sessionID,
+			Tools:         getStrings(data, "tools"),
+			SlashCommands: getStrings(data, "slash_commands"),
+		}
+		msg.CWD, _ = data["cwd"].(string)
+		msg.Model, _ = data["model"].(string)
+		if mode, ok := data["permissionMode"].(string); ok {
+			msg.PermissionMode = PermissionMode(mode)
+		}
+		msg.APIKeySource, _ = data["apiKeySource"].(string)
+		msg.ClaudeCodeVersion, _ = data["claude_code_version"].(string)
+		msg.OutputStyle, _ = data["output_style"].(string)
+		if servers, ok := data["mcp_servers"].([]interface{}); ok {
+			for _, item := range servers {
+				if server, ok := item.(map[string]interface{}); ok {
+					status := MCPServerStatus{}
+					status.Name, _ = server["name"].(string)
+					status.Status, _ = server["status"].(string)
+					msg.MCPServers = append(msg.MCPServers, status)
+				}
+			}
+		}
+		return msg, nil
 
-	durationAPIMS, ok := getInt(data, "duration_api_ms")
-	if !ok {
-		return nil, fmt.Errorf("missing 'duration_api_ms' field in result message")
-	}
+	case "compact_boundary":
+		msg := &CompactBoundaryMessage{
+			SystemMessage: base,
+			SessionID:     sessionID,
+		}
+		if metadata, ok := data["compact_metadata"].(map[string]interface{}); ok {
+			msg.Trigger, _ = metadata["trigger"].(string)
+			msg.PreTokens, _ = getInt(metadata, "pre_tokens")
+		}
+		return msg, nil
 
-	isError, ok := data["is_error"].(bool)
-	if !ok {
-		return nil, fmt.Errorf("missing 'is_error' field in result message")
+	case "api_retry":
+		msg := &APIRetryMessage{
+			SystemMessage: base,
+			SessionID:     sessionID,
+		}
+		msg.Attempt, _ = getInt(data, "attempt")
+		msg.MaxRetries, _ = getInt(data, "max_retries")
+		msg.RetryDelayMS, _ = getInt(data, "retry_delay_ms")
+		msg.ErrorStatus, _ = getInt(data, "error_status")
+		msg.Error, _ = data["error"].(string)
+		return msg, nil
+
+	default:
+		return &base, nil
 	}
+}
 
-	numTurns, ok := getInt(data, "num_turns")
-	if !ok {
-		return nil, fmt.Errorf("missing 'num_turns' field in result message")
+func parseResultMessage(data []byte) (*ResultMessage, error) {
+	var wire struct {
+		Subtype           *string               `json:"subtype"`
+		DurationMS        *int                  `json:"duration_ms"`
+		DurationAPIMS     *int                  `json:"duration_api_ms"`
+		IsError           *bool                 `json:"is_error"`
+		NumTurns          *int                  `json:"num_turns"`
+		SessionID         *string               `json:"session_id"`
+		TotalCostUSD      *float64              `json:"total_cost_usd"`
+		Usage             *Usage                `json:"usage"`
+		Result            *string               `json:"result"`
+		ModelUsage        map[string]ModelUsage `json:"modelUsage"`
+		PermissionDenials []PermissionDenial    `json:"permission_denials"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return nil, fmt.Errorf("invalid result message: %w", err)
 	}
 
-	sessionID, ok := data["session_id"].(string)
-	if !ok {
+	switch {
+	case wire.Subtype == nil:
+		return nil, fmt.Errorf("missing 'subtype' field in result message")
+	case wire.DurationMS == nil:
+		return nil, fmt.Errorf("missing 'duration_ms' field in result message")
+	case wire.DurationAPIMS == nil:
+		return nil, fmt.Errorf("missing 'duration_api_ms' field in result message")
+	case wire.IsError == nil:
+		return nil, fmt.Errorf("missing 'is_error' field in result message")
+	case wire.NumTurns == nil:
+		return nil, fmt.Errorf("missing 'num_turns' field in result message")
+	case wire.SessionID == nil:
 		return nil, fmt.Errorf("missing 'session_id' field in result message")
 	}
 
-	msg := &ResultMessage{
-		Subtype:       subtype,
-		DurationMS:    durationMS,
-		DurationAPIMS: durationAPIMS,
-		IsError:       isError,
-		NumTurns:      numTurns,
-		SessionID:     sessionID,
+	return &ResultMessage{
+		Subtype:           *wire.Subtype,
+		DurationMS:        *wire.DurationMS,
+		DurationAPIMS:     *wire.DurationAPIMS,
+		IsError:           *wire.IsError,
+		NumTurns:          *wire.NumTurns,
+		SessionID:         *wire.SessionID,
+		TotalCostUSD:      wire.TotalCostUSD,
+		Usage:             wire.Usage,
+		Result:            wire.Result,
+		ModelUsage:        wire.ModelUsage,
+		PermissionDenials: wire.PermissionDenials,
+		Raw:               data,
+	}, nil
+}
+
+func parseStreamEvent(data []byte) (*StreamEvent, error) {
+	var wire struct {
+		UUID            string                 `json:"uuid"`
+		SessionID       string                 `json:"session_id"`
+		ParentToolUseID *string                `json:"parent_tool_use_id"`
+		Event           map[string]interface{} `json:"event"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return nil, fmt.Errorf("invalid stream_event message: %w", err)
+	}
+	event := wire.Event
+	if event == nil {
+		return nil, fmt.Errorf("missing 'event' field in stream_event message")
 	}
 
-	// Optional fields
-	if totalCost, exists := data["total_cost_usd"]; exists {
-		if f, ok := totalCost.(float64); ok {
-			msg.TotalCostUSD = &f
-		}
+	eventType, ok := event["type"].(string)
+	if !ok {
+		return nil, fmt.Errorf("stream event missing 'type' field")
 	}
 
-	if usage, exists := data["usage"]; exists {
-		if u, ok := usage.(map[string]interface{}); ok {
-			msg.Usage = u
-		}
+	msg := &StreamEvent{
+		UUID:            wire.UUID,
+		SessionID:       wire.SessionID,
+		ParentToolUseID: wire.ParentToolUseID,
+		EventType:       eventType,
+		Event:           event,
+		Raw:             data,
+	}
+	if index, ok := getInt(event, "index"); ok {
+		msg.Index = index
 	}
 
-	if result, exists := data["result"]; exists {
-		if r, ok := result.(string); ok {
-			msg.Result = &r
-		}
+	if deltaData, ok := event["delta"].(map[string]interface{}); ok && eventType == "content_block_delta" {
+		delta := &StreamDelta{}
+		delta.Type, _ = deltaData["type"].(string)
+		delta.Text, _ = deltaData["text"].(string)
+		delta.Thinking, _ = deltaData["thinking"].(string)
+		delta.PartialJSON, _ = deltaData["partial_json"].(string)
+		delta.Signature, _ = deltaData["signature"].(string)
+		msg.Delta = delta
 	}
 
 	return msg, nil
@@ -252,11 +551,74 @@ func getInt(data map[string]interface{}, key string) (int, bool) {
 	}
 }
 
-// ParseMessageFromJSON parses a JSON byte array into a Message
-func ParseMessageFromJSON(jsonData []byte) (Message, error) {
-	var data map[string]interface{}
-	if err := json.Unmarshal(jsonData, &data); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+// getStrings extracts a list of strings from a map, skipping non-string items
+func getStrings(data map[string]interface{}, key string) []string {
+	items, ok := data[key].([]interface{})
+	if !ok {
+		return nil
 	}
-	return ParseMessage(data)
-}
\ No newline at end of file
+
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// decodeMessage parses a message received from the transport, applying the
+// options' parse error policy. It returns a nil Message and nil error when
+// the message should be skipped.
+func decodeMessage(data MessageData, options *ClaudeCodeOptions) (Message, error) {
+	var msg Message
+	var err error
+	raw := data.Raw
+	if len(raw) > 0 {
+		// The transport has already decoded the type
+		if data.Type == "" {
+			err = fmt.Errorf("message missing 'type' field")
+		} else {
+			msg, err = parseMessageJSON(data.Type, raw)
+		}
+	} else {
+		// Built by hand rather than read from the CLI
+		if raw, err = json.Marshal(data); err != nil {
+			return nil, err
+		}
+		msg, err = ParseMessageFromJSON(raw)
+	}
+	if err == nil {
+		return msg, nil
+	}
+
+	// Only failures pay for a generic decode
+	var dataMap map[string]interface{}
+	json.Unmarshal(raw, &dataMap)
+
+	parseErr := NewMessageParseError(err.Error(), dataMap)
+	parseErr.Cause = err
+
+	if options != nil && options.OnParseError != nil {
+		options.OnParseError(parseErr)
+	}
+
+	policy := ParseErrorSkip
+	if options != nil && options.ParseErrorPolicy != "" {
+		policy = options.ParseErrorPolicy
+	}
+
+	switch policy {
+	case ParseErrorDeliverRaw:
+		return &RawMessage{
+			Type: data.Type,
+			Data: dataMap,
+			Raw:  raw,
+			Err:  parseErr,
+		}, nil
+	case ParseErrorStrict:
+		return nil, parseErr
+	default:
+		return nil, nil
+	}
+}
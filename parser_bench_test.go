@@ -0,0 +1,34 @@
+package claudesdk
+
+import "testing"
+
+// Representative lines of CLI output
+var benchmarkLines = []struct {
+	name string
+	line []byte
+}{
+	{"Assistant", []byte(`{"type":"assistant","message":{"id":"msg_01","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[{"type":"text","text":"Let me look at the failing test first."},{"type":"tool_use","id":"toolu_01","name":"Read","input":{"file_path":"/repo/internal/server/handler_test.go","limit":200}}],"stop_reason":null,"usage":{"input_tokens":1200,"output_tokens":85,"cache_creation_input_tokens":0,"cache_read_input_tokens":15000,"service_tier":"standard"}},"parent_tool_use_id":null,"session_id":"3f2c0a4e-1b7d-4c55-9a0e-2f6b1e8d9c11","uuid":"a1b2c3d4"}`)},
+	{"ToolResult", []byte(`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_01","content":"package server\n\nimport \"testing\"\n\nfunc TestHandler(t *testing.T) {\n\tt.Fatal(\"not implemented\")\n}\n"}]},"parent_tool_use_id":null,"session_id":"3f2c0a4e-1b7d-4c55-9a0e-2f6b1e8d9c11","uuid":"b2c3d4e5"}`)},
+	{"StreamEvent", []byte(`{"type":"stream_event","uuid":"c3d4e5f6","session_id":"3f2c0a4e-1b7d-4c55-9a0e-2f6b1e8d9c11","parent_tool_use_id":null,"event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me look"}}}`)},
+	{"Result", []byte(`{"type":"result","subtype":"success","is_error":false,"duration_ms":48211,"duration_api_ms":45002,"num_turns":12,"result":"All tests pass now.","session_id":"3f2c0a4e-1b7d-4c55-9a0e-2f6b1e8d9c11","total_cost_usd":0.4211,"usage":{"input_tokens":24000,"output_tokens":3100,"cache_creation_input_tokens":5000,"cache_read_input_tokens":180000},"modelUsage":{"claude-sonnet-4-5":{"inputTokens":24000,"outputTokens":3100,"cacheReadInputTokens":180000,"cacheCreationInputTokens":5000,"webSearchRequests":0,"costUSD":0.4211,"contextWindow":200000}},"permission_denials":[],"uuid":"e5f6"}`)},
+}
+
+// BenchmarkDecodeMessage measures the full path of a line from the CLI:
+// the transport's envelope decode followed by parsing into a typed Message
+func BenchmarkDecodeMessage(b *testing.B) {
+	for _, bm := range benchmarkLines {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bm.line)))
+			for i := 0; i < b.N; i++ {
+				data, err := decodeEnvelope(bm.line)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := decodeMessage(data, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package claudesdk
 
 import (
+	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -123,6 +125,84 @@ func TestParseSystemMessage(t *testing.T) {
 	assert.Equal(t, "System information", systemMsg.Data["data"].(map[string]interface{})["message"])
 }
 
+func TestParseTypedSystemMessages(t *testing.T) {
+	t.Run("Init", func(t *testing.T) {
+		msg, err := ParseMessageFromJSON([]byte(`{
+			"type": "system",
+			"subtype": "init",
+			"session_id": "session-123",
+			"cwd": "/repo",
+			"model": "claude-sonnet-4-5",
+			"permissionMode": "acceptEdits",
+			"tools": ["Bash", "Read", "mcp__db__query"],
+			"slash_commands": ["compact", "review"],
+			"mcp_servers": [
+				{"name": "db", "status": "connected"},
+				{"name": "search", "status": "failed"}
+			],
+			"apiKeySource": "ANTHROPIC_API_KEY",
+			"claude_code_version": "2.0.1"
+		}`))
+		require.NoError(t, err)
+
+		initMsg, ok := msg.(*InitMessage)
+		require.True(t, ok)
+		assert.Equal(t, "init", initMsg.Subtype)
+		assert.Equal(t, "session-123", initMsg.SessionID)
+		assert.Equal(t, "/repo", initMsg.CWD)
+		assert.Equal(t, "claude-sonnet-4-5", initMsg.Model)
+		assert.Equal(t, PermissionModeAcceptEdits, initMsg.PermissionMode)
+		assert.Equal(t, []string{"Bash", "Read", "mcp__db__query"}, initMsg.Tools)
+		assert.Equal(t, []string{"compact", "review"}, initMsg.SlashCommands)
+		assert.Equal(t, "2.0.1", initMsg.ClaudeCodeVersion)
+
+		db, ok := initMsg.MCPServer("db")
+		require.True(t, ok)
+		assert.Equal(t, "connected", db.Status)
+		assert.Equal(t, []MCPServerStatus{{Name: "search", Status: "failed"}}, initMsg.FailedMCPServers())
+	})
+
+	t.Run("Compact boundary", func(t *testing.T) {
+		msg, err := ParseMessageFromJSON([]byte(`{
+			"type": "system",
+			"subtype": "compact_boundary",
+			"session_id": "session-123",
+			"compact_metadata": {"trigger": "auto", "pre_tokens": 154000}
+		}`))
+		require.NoError(t, err)
+
+		compact, ok := msg.(*CompactBoundaryMessage)
+		require.True(t, ok)
+		assert.Equal(t, "auto", compact.Trigger)
+		assert.Equal(t, 154000, compact.PreTokens)
+	})
+
+	t.Run("API retry", func(t *testing.T) {
+		msg, err := ParseMessageFromJSON([]byte(`{
+			"type": "system",
+			"subtype": "api_retry",
+			"attempt": 2,
+			"max_retries": 10,
+			"retry_delay_ms": 1200,
+			"error_status": 529,
+			"error": "overloaded_error"
+		}`))
+		require.NoError(t, err)
+
+		retry, ok := msg.(*APIRetryMessage)
+		require.True(t, ok)
+		assert.Equal(t, 2, retry.Attempt)
+		assert.Equal(t, 529, retry.ErrorStatus)
+		assert.Equal(t, "overloaded_error", retry.Error)
+	})
+
+	t.Run("Unknown subtype falls back", func(t *testing.T) {
+		msg, err := ParseMessageFromJSON([]byte(`{"type":"system","subtype":"hook_response"}`))
+		require.NoError(t, err)
+		assert.IsType(t, &SystemMessage{}, msg)
+	})
+}
+
 func TestParseResultMessage(t *testing.T) {
 	data := map[string]interface{}{
 		"type":            "result",
@@ -154,7 +234,8 @@ func TestParseResultMessage(t *testing.T) {
 	assert.NotNil(t, resultMsg.TotalCostUSD)
 	assert.Equal(t, 0.01, *resultMsg.TotalCostUSD)
 	assert.NotNil(t, resultMsg.Usage)
-	assert.Equal(t, float64(100), resultMsg.Usage["input_tokens"])
+	assert.Equal(t, 100, resultMsg.Usage.InputTokens)
+	assert.Equal(t, float64(100), resultMsg.Usage.Raw["input_tokens"])
 	assert.NotNil(t, resultMsg.Result)
 	assert.Equal(t, "completed", *resultMsg.Result)
 }
@@ -178,6 +259,109 @@ func TestParseContentBlocks(t *testing.T) {
 		assert.NotNil(t, toolResult.IsError)
 		assert.True(t, *toolResult.IsError)
 	})
+
+	t.Run("ImageBlock", func(t *testing.T) {
+		block, err := parseContentBlock(map[string]interface{}{
+			"type": "image",
+			"source": map[string]interface{}{
+				"type":       "base64",
+				"media_type": "image/png",
+				"data":       "iVBORw0KGgo=",
+			},
+		})
+		require.NoError(t, err)
+
+		image, ok := block.(*ImageBlock)
+		require.True(t, ok)
+		assert.Equal(t, "base64", image.Source.Type)
+		assert.Equal(t, "image/png", image.Source.MediaType)
+		assert.Equal(t, "iVBORw0KGgo=", image.Source.Data)
+	})
+
+	t.Run("DocumentBlock", func(t *testing.T) {
+		block, err := parseContentBlock(map[string]interface{}{
+			"type":  "document",
+			"title": "Spec",
+			"source": map[string]interface{}{
+				"type": "url",
+				"url":  "https://example.com/spec.pdf",
+			},
+		})
+		require.NoError(t, err)
+
+		doc, ok := block.(*DocumentBlock)
+		require.True(t, ok)
+		assert.Equal(t, "Spec", doc.Title)
+		assert.Equal(t, "https://example.com/spec.pdf", doc.Source.URL)
+	})
+
+	t.Run("RedactedThinkingBlock", func(t *testing.T) {
+		block, err := parseContentBlock(map[string]interface{}{
+			"type": "redacted_thinking",
+			"data": "EmwKAhgBEgy3va3pzix",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "EmwKAhgBEgy3va3pzix", block.(*RedactedThinkingBlock).Data)
+	})
+
+	t.Run("Server tool blocks", func(t *testing.T) {
+		block, err := parseContentBlock(map[string]interface{}{
+			"type":  "server_tool_use",
+			"id":    "srvtoolu_1",
+			"name":  "web_search",
+			"input": map[string]interface{}{"query": "golang iterators"},
+		})
+		require.NoError(t, err)
+		use := block.(*ServerToolUseBlock)
+		assert.Equal(t, "web_search", use.Name)
+		assert.Equal(t, "golang iterators", use.Input["query"])
+
+		block, err = parseContentBlock(map[string]interface{}{
+			"type":        "web_search_tool_result",
+			"tool_use_id": "srvtoolu_1",
+			"content": []interface{}{
+				map[string]interface{}{"type": "web_search_result", "url": "https://go.dev"},
+			},
+		})
+		require.NoError(t, err)
+		result := block.(*WebSearchToolResultBlock)
+		assert.Equal(t, "srvtoolu_1", result.ToolUseID)
+		assert.Len(t, result.Content, 1)
+	})
+
+	t.Run("UnknownBlock", func(t *testing.T) {
+		block, err := parseContentBlock(map[string]interface{}{
+			"type":  "hologram",
+			"depth": float64(3),
+		})
+		require.NoError(t, err)
+
+		unknown, ok := block.(*UnknownBlock)
+		require.True(t, ok)
+		assert.Equal(t, "hologram", unknown.Type)
+		assert.Equal(t, float64(3), unknown.Data["depth"])
+		assert.JSONEq(t, `{"type":"hologram","depth":3}`, string(unknown.Raw))
+	})
+}
+
+func TestAssistantMessageWithUnknownBlock(t *testing.T) {
+	msg, err := ParseMessageFromJSON([]byte(`{
+		"type": "assistant",
+		"message": {
+			"model": "claude-opus-4-1-20250805",
+			"content": [
+				{"type": "text", "text": "Before"},
+				{"type": "future_block", "value": 42},
+				{"type": "text", "text": "After"}
+			]
+		}
+	}`))
+	require.NoError(t, err)
+
+	assistantMsg := msg.(*AssistantMessage)
+	require.Len(t, assistantMsg.Content, 3)
+	assert.IsType(t, &UnknownBlock{}, assistantMsg.Content[1])
+	assert.Equal(t, "After", assistantMsg.Content[2].(*TextBlock).Text)
 }
 
 func TestParseErrors(t *testing.T) {
@@ -214,4 +398,98 @@ func TestParseErrors(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "missing 'model' field")
 	})
-}
\ No newline at end of file
+}
+func TestDecodeMessagePolicy(t *testing.T) {
+	raw := []byte(`{"type":"brand_new","payload":{"x":1}}`)
+	data := MessageData{Type: "brand_new", Raw: raw}
+
+	t.Run("Skip by default", func(t *testing.T) {
+		msg, err := decodeMessage(data, NewClaudeCodeOptions())
+		assert.NoError(t, err)
+		assert.Nil(t, msg)
+	})
+
+	t.Run("Deliver raw", func(t *testing.T) {
+		msg, err := decodeMessage(data, &ClaudeCodeOptions{ParseErrorPolicy: ParseErrorDeliverRaw})
+		require.NoError(t, err)
+
+		rawMsg, ok := msg.(*RawMessage)
+		require.True(t, ok)
+		assert.Equal(t, "brand_new", rawMsg.Type)
+		assert.Equal(t, json.RawMessage(raw), rawMsg.Raw)
+		assert.Equal(t, float64(1), rawMsg.Data["payload"].(map[string]interface{})["x"])
+		assert.Error(t, rawMsg.Err)
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		_, err := decodeMessage(data, &ClaudeCodeOptions{ParseErrorPolicy: ParseErrorStrict})
+		var parseErr *MessageParseError
+		require.True(t, errors.As(err, &parseErr))
+		assert.Contains(t, parseErr.Error(), "unknown message type")
+	})
+
+	t.Run("Callback", func(t *testing.T) {
+		var reported []*MessageParseError
+		options := &ClaudeCodeOptions{
+			OnParseError: func(err *MessageParseError) {
+				reported = append(reported, err)
+			},
+		}
+
+		msg, err := decodeMessage(data, options)
+		assert.NoError(t, err)
+		assert.Nil(t, msg)
+		require.Len(t, reported, 1)
+		assert.Equal(t, "brand_new", reported[0].Data.(map[string]interface{})["type"])
+	})
+
+	t.Run("Raw keeps undeclared fields", func(t *testing.T) {
+		line := []byte(`{"type":"system","subtype":"init","cwd":"/repo"}`)
+		msg, err := decodeMessage(MessageData{Type: "system", Subtype: "init", Raw: line}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "/repo", msg.(*InitMessage).Data["cwd"])
+	})
+}
+
+func TestParseKeepsUnknownFields(t *testing.T) {
+	line := []byte(`{"type":"assistant","message":{"id":"msg_01","model":"claude-sonnet-4-5","stop_reason":"tool_use","content":[{"type":"text","text":"hi"}]},"uuid":"u1"}`)
+	msg, err := ParseMessageFromJSON(line)
+	require.NoError(t, err)
+
+	assistantMsg := msg.(*AssistantMessage)
+	var extra struct {
+		UUID    string `json:"uuid"`
+		Message struct {
+			ID         string `json:"id"`
+			StopReason string `json:"stop_reason"`
+		} `json:"message"`
+	}
+	require.NoError(t, json.Unmarshal(assistantMsg.Raw, &extra))
+	assert.Equal(t, "u1", extra.UUID)
+	assert.Equal(t, "msg_01", extra.Message.ID)
+	assert.Equal(t, "tool_use", extra.Message.StopReason)
+
+	// Marshaling a typed message doesn't leak Raw
+	out, err := json.Marshal(assistantMsg)
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "msg_01")
+}
+
+func TestParseInvalidFieldTypes(t *testing.T) {
+	for _, line := range []string{
+		`{"type":"assistant","message":"not an object"}`,
+		`{"type":"assistant","message":{"model":"m","content":"not a list"}}`,
+		`{"type":"user","message":{"content":[42]}}`,
+		`{"type":"result","subtype":"success","duration_ms":"slow"}`,
+	} {
+		_, err := ParseMessageFromJSON([]byte(line))
+		assert.Error(t, err, line)
+	}
+}
+
+func TestMapToMessageData(t *testing.T) {
+	// A non-map message must not panic
+	data := mapToMessageData(map[string]interface{}{"type": "user", "message": "hello"})
+	assert.Equal(t, "user", data.Type)
+	assert.Nil(t, data.Message)
+}
@@ -0,0 +1,90 @@
+package claudesdk
+
+import (
+	"context"
+	"fmt"
+)
+
+// PermissionBehavior is the outcome of a tool permission check
+type PermissionBehavior string
+
+const (
+	PermissionBehaviorAllow PermissionBehavior = "allow"
+	PermissionBehaviorDeny  PermissionBehavior = "deny"
+)
+
+// PermissionDecision is returned by a CanUseToolFunc to allow or deny a tool call
+type PermissionDecision struct {
+	Behavior PermissionBehavior
+	// UpdatedInput replaces the tool input when allowing. If nil, the
+	// original input is used unchanged.
+	UpdatedInput map[string]interface{}
+	// Message explains a denial to Claude
+	Message string
+	// Interrupt stops the current turn in addition to denying the tool call
+	Interrupt bool
+}
+
+// CanUseToolFunc decides whether Claude may run a tool with the given input.
+// Returning an error denies the tool call with the error's message.
+type CanUseToolFunc func(ctx context.Context, toolName string, input map[string]interface{}) (PermissionDecision, error)
+
+// Allow returns a decision that lets the tool call run unchanged
+func Allow() PermissionDecision {
+	return PermissionDecision{Behavior: PermissionBehaviorAllow}
+}
+
+// AllowWithInput returns a decision that lets the tool call run with a rewritten input
+func AllowWithInput(input map[string]interface{}) PermissionDecision {
+	return PermissionDecision{Behavior: PermissionBehaviorAllow, UpdatedInput: input}
+}
+
+// Deny returns a decision that rejects the tool call with the given message
+func Deny(message string) PermissionDecision {
+	return PermissionDecision{Behavior: PermissionBehaviorDeny, Message: message}
+}
+
+// canUseToolHandler adapts a CanUseToolFunc to the "can_use_tool" control request
+func canUseToolHandler(canUseTool CanUseToolFunc) ControlRequestHandler {
+	return func(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error) {
+		toolName, ok := request["tool_name"].(string)
+		if !ok {
+			return nil, fmt.Errorf("can_use_tool request missing 'tool_name' field")
+		}
+		input, _ := request["input"].(map[string]interface{})
+		if input == nil {
+			input = map[string]interface{}{}
+		}
+
+		decision, err := canUseTool(ctx, toolName, input)
+		if err != nil {
+			return map[string]interface{}{
+				"behavior": string(PermissionBehaviorDeny),
+				"message":  err.Error(),
+			}, nil
+		}
+
+		switch decision.Behavior {
+		case PermissionBehaviorAllow:
+			updatedInput := decision.UpdatedInput
+			if updatedInput == nil {
+				updatedInput = input
+			}
+			return map[string]interface{}{
+				"behavior":     string(PermissionBehaviorAllow),
+				"updatedInput": updatedInput,
+			}, nil
+		case PermissionBehaviorDeny:
+			response := map[string]interface{}{
+				"behavior": string(PermissionBehaviorDeny),
+				"message":  decision.Message,
+			}
+			if decision.Interrupt {
+				response["interrupt"] = true
+			}
+			return response, nil
+		default:
+			return nil, fmt.Errorf("invalid permission behavior: %q", decision.Behavior)
+		}
+	}
+}
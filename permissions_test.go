@@ -0,0 +1,128 @@
+package claudesdk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanUseTool(t *testing.T) {
+	canUseTool := func(ctx context.Context, toolName string, input map[string]interface{}) (PermissionDecision, error) {
+		switch toolName {
+		case "Read":
+			return Allow(), nil
+		case "Bash":
+			if strings.HasPrefix(input["command"].(string), "rm ") {
+				return Deny("destructive commands are not allowed"), nil
+			}
+			return AllowWithInput(map[string]interface{}{"command": input["command"].(string) + " --dry-run"}), nil
+		default:
+			return PermissionDecision{}, fmt.Errorf("policy service unavailable")
+		}
+	}
+
+	permissionRequest := func(t *testing.T, cli *fakeCLI, toolName string, input map[string]interface{}) map[string]interface{} {
+		cli.send(t, map[string]interface{}{
+			"type":       "control_request",
+			"request_id": "perm_" + toolName,
+			"request": map[string]interface{}{
+				"subtype":   "can_use_tool",
+				"tool_name": toolName,
+				"input":     input,
+			},
+		})
+		body := cli.readLine(t)["response"].(map[string]interface{})
+		require.Equal(t, "success", body["subtype"])
+		return body["response"].(map[string]interface{})
+	}
+
+	_, cli := newTestTransport(t, &ClaudeCodeOptions{CanUseTool: canUseTool})
+
+	t.Run("Allow unchanged", func(t *testing.T) {
+		resp := permissionRequest(t, cli, "Read", map[string]interface{}{"file_path": "/tmp/a"})
+		assert.Equal(t, "allow", resp["behavior"])
+		assert.Equal(t, "/tmp/a", resp["updatedInput"].(map[string]interface{})["file_path"])
+	})
+
+	t.Run("Allow with rewritten input", func(t *testing.T) {
+		resp := permissionRequest(t, cli, "Bash", map[string]interface{}{"command": "make"})
+		assert.Equal(t, "allow", resp["behavior"])
+		assert.Equal(t, "make --dry-run", resp["updatedInput"].(map[string]interface{})["command"])
+	})
+
+	t.Run("Deny", func(t *testing.T) {
+		resp := permissionRequest(t, cli, "Bash", map[string]interface{}{"command": "rm -rf /"})
+		assert.Equal(t, "deny", resp["behavior"])
+		assert.Equal(t, "destructive commands are not allowed", resp["message"])
+	})
+
+	t.Run("Callback error denies", func(t *testing.T) {
+		resp := permissionRequest(t, cli, "Write", map[string]interface{}{})
+		assert.Equal(t, "deny", resp["behavior"])
+		assert.Equal(t, "policy service unavailable", resp["message"])
+	})
+}
+
+func TestCanUseToolOptions(t *testing.T) {
+	allowAll := func(ctx context.Context, toolName string, input map[string]interface{}) (PermissionDecision, error) {
+		return Allow(), nil
+	}
+
+	t.Run("String prompt switches to streaming", func(t *testing.T) {
+		transport, err := NewSubprocessCLITransport("hello", &ClaudeCodeOptions{CanUseTool: allowAll}, "claude", true)
+		require.NoError(t, err)
+
+		assert.True(t, transport.isStreaming)
+		cmd := strings.Join(transport.buildCommand(), " ")
+		assert.Contains(t, cmd, "--permission-prompt-tool stdio")
+		assert.Contains(t, cmd, "--input-format stream-json")
+		assert.NotContains(t, cmd, "--print")
+	})
+
+	t.Run("Stdin stays open for every turn", func(t *testing.T) {
+		transport, cli := newTestTransport(t, &ClaudeCodeOptions{CanUseTool: allowAll})
+
+		prompt := make(chan map[string]interface{}, 2)
+		for _, text := range []string{"one", "two"} {
+			prompt <- map[string]interface{}{"type": "user", "message": map[string]interface{}{"role": "user", "content": text}}
+		}
+		close(prompt)
+		transport.prompt = prompt
+		transport.closeStdinAfterPrompt = true
+		go transport.streamInput()
+
+		cli.readLine(t)
+		cli.readLine(t)
+		cli.send(t, resultMessage("s1"))
+		// Give a premature close of stdin time to happen
+		time.Sleep(50 * time.Millisecond)
+
+		// The second turn can still ask for permission
+		cli.send(t, map[string]interface{}{
+			"type":       "control_request",
+			"request_id": "perm_1",
+			"request": map[string]interface{}{
+				"subtype":   "can_use_tool",
+				"tool_name": "Read",
+				"input":     map[string]interface{}{},
+			},
+		})
+		assert.Equal(t, "control_response", cli.readLine(t)["type"])
+
+		cli.send(t, resultMessage("s1"))
+		assert.False(t, cli.stdin.Scan(), "stdin should be closed after the last result")
+	})
+
+	t.Run("Conflicts with PermissionPromptToolName", func(t *testing.T) {
+		_, err := NewSubprocessCLITransport("hello", &ClaudeCodeOptions{
+			CanUseTool:               allowAll,
+			PermissionPromptToolName: String("mcp__approver"),
+		}, "claude", true)
+		assert.Error(t, err)
+	})
+}
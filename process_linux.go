@@ -0,0 +1,20 @@
+package claudesdk
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the CLI as the leader of a new process group so
+// that its children can be signalled together with it
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// signalProcessGroup sends sig to every process in the CLI's group
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	return syscall.Kill(-cmd.Process.Pid, sig)
+}
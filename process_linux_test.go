@@ -0,0 +1,68 @@
+package claudesdk
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// processAlive reports whether pid is running (zombies count as exited)
+func processAlive(pid int) bool {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(stat))
+	return len(fields) > 2 && fields[2] != "Z"
+}
+
+func TestProcessGroup(t *testing.T) {
+	// The CLI leaves a child behind, as a stdio MCP server would
+	start := func(t *testing.T, options *ClaudeCodeOptions) (*SubprocessCLITransport, int) {
+		t.Helper()
+		pidFile := filepath.Join(t.TempDir(), "child.pid")
+		options.Env = map[string]string{"PID_FILE": pidFile}
+		options.ShutdownGracePeriod = 100 * time.Millisecond
+
+		cli := writeFakeCLIScript(t, `sleep 30 >/dev/null &
+echo $! > "$PID_FILE"
+exec sleep 30
+`)
+		transport, err := NewSubprocessCLITransport("hello", options, cli, true)
+		require.NoError(t, err)
+		require.NoError(t, transport.Connect())
+
+		var pid int
+		require.Eventually(t, func() bool {
+			data, err := os.ReadFile(pidFile)
+			if err != nil {
+				return false
+			}
+			pid, err = strconv.Atoi(strings.TrimSpace(string(data)))
+			return err == nil
+		}, 2*time.Second, 10*time.Millisecond)
+		return transport, pid
+	}
+
+	t.Run("Children are terminated", func(t *testing.T) {
+		transport, pid := start(t, &ClaudeCodeOptions{})
+		require.NoError(t, transport.Disconnect())
+
+		assert.Eventually(t, func() bool { return !processAlive(pid) }, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Opt out", func(t *testing.T) {
+		transport, pid := start(t, &ClaudeCodeOptions{DisableProcessGroup: true})
+		defer syscall.Kill(pid, syscall.SIGKILL)
+		require.NoError(t, transport.Disconnect())
+
+		assert.True(t, processAlive(pid))
+	})
+}
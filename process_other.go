@@ -0,0 +1,20 @@
+//go:build !linux
+
+package claudesdk
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup is a no-op where process groups are not supported
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalProcessGroup signals only the CLI itself where process groups are
+// not supported
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	if sig == syscall.SIGKILL {
+		return cmd.Process.Kill()
+	}
+	return cmd.Process.Signal(sig)
+}
@@ -2,7 +2,8 @@ package claudesdk
 
 import (
 	"context"
-	"os"
+	"errors"
+	"sync/atomic"
 )
 
 // Query performs a one-shot interaction with Claude Code.
@@ -54,83 +55,167 @@ import (
 //	for msg := range Query(ctx, "Create a Python web server", options) {
 //	    fmt.Println(msg)
 //	}
+//
+// If the query fails, the error is delivered in-band as a final SystemMessage
+// with Subtype "error". Use QueryStream to receive failures as typed errors.
 func Query(ctx context.Context, prompt interface{}, options *ClaudeCodeOptions) <-chan Message {
 	msgChan := make(chan Message)
 
 	go func() {
 		defer close(msgChan)
 
-		if options == nil {
-			options = NewClaudeCodeOptions()
-		}
-
-		os.Setenv("CLAUDE_CODE_ENTRYPOINT", "sdk-go")
+		stream := QueryStream(ctx, prompt, options)
+		defer stream.Close()
 
-		// Create transport with closeStdinAfterPrompt=true for one-shot mode
-		t, err := NewSubprocessCLITransport(prompt, options, "", true)
-		if err != nil {
-			// Send error as a system message
-			msgChan <- &SystemMessage{
-				Subtype: "error",
-				Data: map[string]interface{}{
-					"error": err.Error(),
-				},
+		for msg := range stream.Messages() {
+			select {
+			case msgChan <- msg:
+			case <-ctx.Done():
+				return
 			}
-			return
 		}
 
-		// Connect
-		if err := t.Connect(); err != nil {
+		if err := stream.Err(); err != nil && ctx.Err() == nil {
 			msgChan <- &SystemMessage{
 				Subtype: "error",
 				Data: map[string]interface{}{
 					"error": err.Error(),
 				},
 			}
-			return
 		}
-		defer t.Disconnect()
+	}()
 
-		// Receive messages
-		dataChan, err := t.ReceiveMessages()
-		if err != nil {
-			msgChan <- &SystemMessage{
-				Subtype: "error",
-				Data: map[string]interface{}{
-					"error": err.Error(),
-				},
-			}
-			return
+	return msgChan
+}
+
+// MessageStream delivers the messages of a query together with the error
+// that ended it.
+type MessageStream struct {
+	messages  chan Message
+	done      chan struct{}
+	cancel    context.CancelFunc
+	closed    atomic.Bool
+	err       error
+	streamErr error
+}
+
+// Messages returns the channel of messages. It is closed when the query
+// finishes, fails or is closed.
+func (s *MessageStream) Messages() <-chan Message {
+	return s.messages
+}
+
+// Err blocks until the stream has finished and returns the error that ended
+// it, or nil on success. The error keeps its concrete type, so errors.As
+// works for *CLINotFoundError, *CLIConnectionError and *ProcessError. If the
+// context was cancelled, Err returns the context's error. Skipped lines of
+// output don't end the stream; they are reported by StreamErr.
+func (s *MessageStream) Err() error {
+	<-s.done
+	return s.err
+}
+
+// StreamErr blocks until the stream has finished and returns the first line
+// of CLI output that was skipped, as *JSONDecodeError or
+// *MessageTooLargeError, or nil if none was.
+func (s *MessageStream) StreamErr() error {
+	<-s.done
+	return s.streamErr
+}
+
+// Close stops the query early and terminates the CLI subprocess. It is safe
+// to call more than once and after the stream has finished. Stopping a
+// stream with Close is not an error.
+func (s *MessageStream) Close() {
+	s.closed.Store(true)
+	s.cancel()
+	<-s.done
+}
+
+// QueryStream performs a one-shot interaction like Query, but reports
+// failures as errors instead of in-band messages.
+//
+// Example:
+//
+//	stream := QueryStream(ctx, "What is 2+2?", nil)
+//	defer stream.Close()
+//	for msg := range stream.Messages() {
+//	    fmt.Println(msg)
+//	}
+//	var notFound *CLINotFoundError
+//	if err := stream.Err(); errors.As(err, &notFound) {
+//	    log.Fatal("install Claude Code first")
+//	}
+func QueryStream(ctx context.Context, prompt interface{}, options *ClaudeCodeOptions) *MessageStream {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &MessageStream{
+		messages: make(chan Message),
+		done:     make(chan struct{}),
+		cancel:   cancel,
+	}
+
+	go func() {
+		defer close(s.done)
+		defer close(s.messages)
+		err := s.run(ctx, prompt, options)
+		if s.closed.Load() && errors.Is(err, context.Canceled) {
+			err = nil
 		}
+		s.err = err
+	}()
+
+	return s
+}
+
+// run runs a one-shot query, sending parsed messages to s.messages
+func (s *MessageStream) run(ctx context.Context, prompt interface{}, options *ClaudeCodeOptions) error {
+	if options == nil {
+		options = NewClaudeCodeOptions()
+	}
+
+	// Create transport with closeStdinAfterPrompt=true for one-shot mode
+	t, err := NewSubprocessCLITransport(prompt, options, "", true)
+	if err != nil {
+		return err
+	}
+
+	if err := t.ConnectContext(ctx); err != nil {
+		return err
+	}
+	// Read once the reader has stopped, after Disconnect
+	defer func() { s.streamErr = t.StreamErr() }()
+	defer t.Disconnect()
+
+	dataChan, err := t.ReceiveMessages()
+	if err != nil {
+		return err
+	}
+
+	// Parse and forward messages
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case data, ok := <-dataChan:
+			if !ok {
+				return t.Err()
+			}
+
+			msg, err := decodeMessage(data, options)
+			if err != nil {
+				return err
+			}
+			if msg == nil {
+				continue
+			}
 
-		// Parse and forward messages
-		for {
 			select {
+			case s.messages <- msg:
 			case <-ctx.Done():
-				return
-			case data, ok := <-dataChan:
-				if !ok {
-					return
-				}
-
-				// Convert MessageData to map for parser
-				dataMap := messageDataToMap(data)
-				msg, err := ParseMessage(dataMap)
-				if err != nil {
-					// Log error and continue
-					continue
-				}
-
-				select {
-				case msgChan <- msg:
-				case <-ctx.Done():
-					return
-				}
+				return ctx.Err()
 			}
 		}
-	}()
-
-	return msgChan
+	}
 }
 
 // QuerySync performs a synchronous query and returns all messages
@@ -138,6 +223,10 @@ func Query(ctx context.Context, prompt interface{}, options *ClaudeCodeOptions)
 // This is a convenience wrapper around Query that collects all messages
 // and returns them as a slice. Useful when you want all results at once.
 //
+// If the query ran but ended with an error result, the error from
+// ResultMessage.Err is returned (for example *MaxTurnsError) along with
+// the messages.
+//
 // Example:
 //
 //	messages, err := QuerySync(ctx, "What is 2+2?", nil)
@@ -149,18 +238,22 @@ func Query(ctx context.Context, prompt interface{}, options *ClaudeCodeOptions)
 //	}
 func QuerySync(ctx context.Context, prompt interface{}, options *ClaudeCodeOptions) ([]Message, error) {
 	var messages []Message
-	
-	for msg := range Query(ctx, prompt, options) {
+	var result *ResultMessage
+
+	stream := QueryStream(ctx, prompt, options)
+	for msg := range stream.Messages() {
 		messages = append(messages, msg)
-		
-		// Check if this is an error message
-		if sysMsg, ok := msg.(*SystemMessage); ok && sysMsg.Subtype == "error" {
-			if errStr, ok := sysMsg.Data["error"].(string); ok {
-				return messages, NewCLIConnectionError(errStr)
-			}
+		if r, ok := msg.(*ResultMessage); ok {
+			result = r
 		}
 	}
-	
+
+	if err := stream.Err(); err != nil {
+		return messages, err
+	}
+	if result != nil {
+		return messages, result.Err()
+	}
 	return messages, nil
 }
 
@@ -0,0 +1,102 @@
+package claudesdk
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useFakeCLI puts a fake claude script first on PATH for the duration of the test
+func useFakeCLI(t *testing.T, script string) {
+	t.Helper()
+
+	path := writeFakeCLIScript(t, script)
+	t.Setenv("PATH", filepath.Dir(path)+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestQueryStream(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		useFakeCLI(t, `echo '{"type":"assistant","message":{"model":"claude","content":[{"type":"text","text":"4"}]}}'
+echo '{"type":"result","subtype":"success","duration_ms":10,"duration_api_ms":5,"is_error":false,"num_turns":1,"session_id":"s1"}'
+`)
+
+		messages, err := QuerySync(context.Background(), "What is 2+2?", nil)
+		require.NoError(t, err)
+		require.Len(t, messages, 2)
+		assert.IsType(t, &AssistantMessage{}, messages[0])
+		assert.IsType(t, &ResultMessage{}, messages[1])
+	})
+
+	t.Run("Error result", func(t *testing.T) {
+		useFakeCLI(t, `echo '{"type":"result","subtype":"error_max_turns","duration_ms":10,"duration_api_ms":5,"is_error":true,"num_turns":3,"session_id":"s1"}'
+`)
+
+		messages, err := QuerySync(context.Background(), "hello", &ClaudeCodeOptions{MaxTurns: Int(3)})
+		assert.Len(t, messages, 1)
+		var maxTurns *MaxTurnsError
+		require.True(t, errors.As(err, &maxTurns))
+		assert.Equal(t, 3, maxTurns.NumTurns)
+		assert.Equal(t, "s1", maxTurns.SessionID)
+	})
+
+	t.Run("Skipped line is not a failure", func(t *testing.T) {
+		useFakeCLI(t, `echo 'not json at all'
+echo '{"type":"result","subtype":"error_max_turns","duration_ms":10,"duration_api_ms":5,"is_error":true,"num_turns":3,"session_id":"s1"}'
+`)
+
+		messages, err := QuerySync(context.Background(), "hello", nil)
+		assert.Len(t, messages, 1)
+		var maxTurns *MaxTurnsError
+		assert.True(t, errors.As(err, &maxTurns))
+
+		stream := QueryStream(context.Background(), "hello", nil)
+		for range stream.Messages() {
+		}
+		assert.NoError(t, stream.Err())
+		var decodeErr *JSONDecodeError
+		assert.True(t, errors.As(stream.StreamErr(), &decodeErr))
+	})
+
+	t.Run("CLI crashed", func(t *testing.T) {
+		useFakeCLI(t, `echo "out of memory" >&2
+exit 137
+`)
+
+		stream := QueryStream(context.Background(), "hello", nil)
+		for range stream.Messages() {
+		}
+
+		var processErr *ProcessError
+		require.True(t, errors.As(stream.Err(), &processErr))
+		assert.Equal(t, 137, processErr.ExitCode)
+		assert.Equal(t, "out of memory", processErr.Stderr)
+	})
+
+	t.Run("Close stops early", func(t *testing.T) {
+		useFakeCLI(t, `echo '{"type":"system","subtype":"init"}'
+sleep 30
+`)
+
+		stream := QueryStream(context.Background(), "hello", nil)
+		<-stream.Messages()
+		stream.Close()
+		assert.NoError(t, stream.Err())
+	})
+
+	t.Run("Query reports errors in-band", func(t *testing.T) {
+		useFakeCLI(t, `exit 1`)
+
+		var last Message
+		for msg := range Query(context.Background(), "hello", nil) {
+			last = msg
+		}
+		sysMsg, ok := last.(*SystemMessage)
+		require.True(t, ok)
+		assert.Equal(t, "error", sysMsg.Subtype)
+	})
+}
@@ -0,0 +1,151 @@
+package claudesdk
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Response is one turn collected into a single value: every message, in
+// order, and the ResultMessage that ended it.
+type Response struct {
+	Messages []Message
+	result   *ResultMessage
+}
+
+// ToolCall is a tool use paired with its result, if the turn reported one
+type ToolCall struct {
+	Use    *ToolUseBlock
+	Result *ToolResultBlock
+}
+
+// newResponse collects messages into a Response
+func newResponse(messages []Message) *Response {
+	r := &Response{Messages: messages}
+	for _, msg := range messages {
+		if result, ok := msg.(*ResultMessage); ok {
+			r.result = result
+		}
+	}
+	return r
+}
+
+// Result returns the turn's ResultMessage, or nil if it ended without one
+func (r *Response) Result() *ResultMessage {
+	return r.result
+}
+
+// Text returns the final answer: the result text reported by the CLI, or
+// else the text of the last assistant message.
+func (r *Response) Text() string {
+	if r.result != nil && r.result.Result != nil {
+		return *r.result.Result
+	}
+	for i := len(r.Messages) - 1; i >= 0; i-- {
+		msg, ok := r.Messages[i].(*AssistantMessage)
+		if !ok {
+			continue
+		}
+		var text []string
+		for _, block := range msg.Content {
+			if b, ok := block.(*TextBlock); ok {
+				text = append(text, b.Text)
+			}
+		}
+		if len(text) > 0 {
+			return strings.Join(text, "")
+		}
+	}
+	return ""
+}
+
+// Thinking returns the text of all thinking blocks, separated by blank lines
+func (r *Response) Thinking() string {
+	var thinking []string
+	for _, msg := range r.Messages {
+		if msg, ok := msg.(*AssistantMessage); ok {
+			for _, block := range msg.Content {
+				if b, ok := block.(*ThinkingBlock); ok {
+					thinking = append(thinking, b.Thinking)
+				}
+			}
+		}
+	}
+	return strings.Join(thinking, "\n\n")
+}
+
+// ToolCalls returns every tool the assistant used, in order, each with the
+// result the CLI sent back for it
+func (r *Response) ToolCalls() []ToolCall {
+	var calls []ToolCall
+	byID := make(map[string]int)
+	for _, msg := range r.Messages {
+		switch msg := msg.(type) {
+		case *AssistantMessage:
+			for _, block := range msg.Content {
+				if b, ok := block.(*ToolUseBlock); ok {
+					byID[b.ID] = len(calls)
+					calls = append(calls, ToolCall{Use: b})
+				}
+			}
+		case *UserMessage:
+			blocks, _ := msg.Content.([]ContentBlock)
+			for _, block := range blocks {
+				if b, ok := block.(*ToolResultBlock); ok {
+					if i, found := byID[b.ToolUseID]; found {
+						calls[i].Result = b
+					}
+				}
+			}
+		}
+	}
+	return calls
+}
+
+// Usage returns the turn's token usage, or nil if unknown
+func (r *Response) Usage() *Usage {
+	if r.result == nil {
+		return nil
+	}
+	return r.result.Usage
+}
+
+// CostUSD returns the cost reported by the CLI, or 0 if unknown
+func (r *Response) CostUSD() float64 {
+	if r.result == nil || r.result.TotalCostUSD == nil {
+		return 0
+	}
+	return *r.result.TotalCostUSD
+}
+
+// SessionID returns the session the turn belongs to
+func (r *Response) SessionID() string {
+	if r.result == nil {
+		return ""
+	}
+	return r.result.SessionID
+}
+
+// Duration returns how long the turn took
+func (r *Response) Duration() time.Duration {
+	if r.result == nil {
+		return 0
+	}
+	return time.Duration(r.result.DurationMS) * time.Millisecond
+}
+
+// Ask performs a one-shot query like QuerySync and collects the answer into
+// a Response. A failed turn, such as one that hit the turn limit, is
+// returned along with its error.
+//
+// Example:
+//
+//	resp, err := Ask(ctx, "What is 2+2?", nil)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Println(resp.Text())
+func Ask(ctx context.Context, prompt interface{}, options *ClaudeCodeOptions) (*Response, error) {
+	messages, err := QuerySync(ctx, prompt, options)
+	return newResponse(messages), err
+}
@@ -0,0 +1,119 @@
+package claudesdk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponse(t *testing.T) {
+	t.Run("Accessors", func(t *testing.T) {
+		cost := 0.25
+		isError := false
+		read := &ToolUseBlock{ID: "t1", Name: "Read", Input: map[string]interface{}{"file_path": "a.go"}}
+		grep := &ToolUseBlock{ID: "t2", Name: "Grep"}
+		readResult := &ToolResultBlock{ToolUseID: "t1", Content: "package a", IsError: &isError}
+
+		resp := newResponse([]Message{
+			&AssistantMessage{Content: []ContentBlock{
+				&ThinkingBlock{Thinking: "look at the file"},
+				read,
+				grep,
+			}},
+			&UserMessage{Content: []ContentBlock{readResult}},
+			&AssistantMessage{Content: []ContentBlock{
+				&ThinkingBlock{Thinking: "done"},
+				&TextBlock{Text: "It is "},
+				&TextBlock{Text: "package a."},
+			}},
+			&ResultMessage{
+				Subtype:      ResultSubtypeSuccess,
+				DurationMS:   1500,
+				SessionID:    "s1",
+				TotalCostUSD: &cost,
+				Usage:        &Usage{InputTokens: 10, OutputTokens: 5},
+			},
+		})
+
+		assert.Equal(t, "It is package a.", resp.Text())
+		assert.Equal(t, "look at the file\n\ndone", resp.Thinking())
+		assert.Equal(t, []ToolCall{{Use: read, Result: readResult}, {Use: grep}}, resp.ToolCalls())
+		assert.Equal(t, "s1", resp.SessionID())
+		assert.Equal(t, 0.25, resp.CostUSD())
+		assert.Equal(t, 1500*time.Millisecond, resp.Duration())
+		assert.Equal(t, 15, resp.Usage().TotalTokens())
+		assert.Len(t, resp.Messages, 4)
+	})
+
+	t.Run("Result text wins", func(t *testing.T) {
+		resp := newResponse([]Message{
+			&AssistantMessage{Content: []ContentBlock{&TextBlock{Text: "draft"}}},
+			&ResultMessage{Subtype: ResultSubtypeSuccess, Result: String("final")},
+		})
+		assert.Equal(t, "final", resp.Text())
+	})
+
+	t.Run("No result", func(t *testing.T) {
+		resp := newResponse(nil)
+		assert.Nil(t, resp.Result())
+		assert.Empty(t, resp.Text())
+		assert.Nil(t, resp.Usage())
+		assert.Zero(t, resp.CostUSD())
+		assert.Empty(t, resp.SessionID())
+		assert.Zero(t, resp.Duration())
+	})
+}
+
+func TestAsk(t *testing.T) {
+	t.Run("Client", func(t *testing.T) {
+		client, cli := newTurnClient(t, nil)
+
+		go func() {
+			cli.send(t, map[string]interface{}{
+				"type": "assistant",
+				"message": map[string]interface{}{
+					"model":   "claude",
+					"content": []interface{}{map[string]interface{}{"type": "text", "text": "4"}},
+				},
+			})
+			cli.send(t, resultMessage("s1"))
+		}()
+
+		resp, err := client.Ask(context.Background(), "What is 2 + 2?")
+		require.NoError(t, err)
+		assert.Equal(t, "4", resp.Text())
+		assert.Equal(t, "s1", resp.SessionID())
+	})
+
+	t.Run("Client failed turn", func(t *testing.T) {
+		client, cli := newTurnClient(t, nil)
+
+		go func() {
+			result := resultMessage("s1")
+			result["subtype"] = ResultSubtypeErrorMaxTurns
+			result["is_error"] = true
+			cli.send(t, result)
+		}()
+
+		resp, err := client.Ask(context.Background(), "hello")
+		var maxTurns *MaxTurnsError
+		assert.True(t, errors.As(err, &maxTurns))
+		require.NotNil(t, resp)
+		assert.NotNil(t, resp.Result())
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		useFakeCLI(t, `echo '{"type":"assistant","message":{"model":"claude","content":[{"type":"text","text":"4"}]}}'
+echo '{"type":"result","subtype":"success","duration_ms":10,"duration_api_ms":5,"is_error":false,"num_turns":1,"session_id":"s1","result":"4"}'
+`)
+
+		resp, err := Ask(context.Background(), "What is 2 + 2?", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "4", resp.Text())
+		assert.Equal(t, 10*time.Millisecond, resp.Duration())
+	})
+}
@@ -0,0 +1,121 @@
+package claudesdk
+
+import "context"
+
+// MessageSessionID returns the ID of the session a message belongs to, or
+// "" if the message does not carry one.
+//
+// This is the CLI's own session ID. The CLI keeps one conversation per
+// process, so every message from a Client carries the same ID whatever
+// sessionID was passed to Query or Send; it changes only when the CLI
+// starts a new session, such as after a resume or fork.
+func MessageSessionID(msg Message) string {
+	switch m := msg.(type) {
+	case *UserMessage:
+		return m.SessionID
+	case *AssistantMessage:
+		return m.SessionID
+	case *ResultMessage:
+		return m.SessionID
+	case *StreamEvent:
+		return m.SessionID
+	case *InitMessage:
+		return m.SessionID
+	case *CompactBoundaryMessage:
+		return m.SessionID
+	case *APIRetryMessage:
+		return m.SessionID
+	case *SystemMessage:
+		sessionID, _ := m.Data["session_id"].(string)
+		return sessionID
+	case *RawMessage:
+		sessionID, _ := m.Data["session_id"].(string)
+		return sessionID
+	}
+	return ""
+}
+
+// MessageParentToolUseID returns the ID of the tool use that started the
+// subagent a message comes from, or "" for the main conversation
+func MessageParentToolUseID(msg Message) string {
+	var id *string
+	switch m := msg.(type) {
+	case *UserMessage:
+		id = m.ParentToolUseID
+	case *AssistantMessage:
+		id = m.ParentToolUseID
+	case *StreamEvent:
+		id = m.ParentToolUseID
+	case *RawMessage:
+		s, _ := m.Data["parent_tool_use_id"].(string)
+		return s
+	}
+	if id == nil {
+		return ""
+	}
+	return *id
+}
+
+// SubscribeToolUse is like Subscribe but receives only the messages of the
+// subagent started by the tool use toolUseID, such as a Task call. It ends
+// without error once the tool's result or the turn's ResultMessage
+// arrives. With an empty toolUseID it receives the main conversation
+// without any subagent messages, until the session ends.
+//
+// Like any observer it sees only what arrives after it subscribes, and
+// never takes messages held for receivers. To catch a subagent from its
+// first message, subscribe from a PreToolUse hook, whose callback is given
+// the tool use ID before the tool runs.
+//
+// Messages can't be separated by session this way: the CLI runs one
+// conversation per process, whatever sessionID is passed to Query or Send
+// (see MessageSessionID). Use a Client per conversation.
+//
+// Example:
+//
+//	// Show the main conversation without subagent chatter
+//	sub, err := client.SubscribeToolUse(ctx, "")
+//	if err != nil {
+//	    return err
+//	}
+//	defer sub.Unsubscribe()
+//	for msg := range sub.Messages() {
+//	    fmt.Println(msg)
+//	}
+func (c *Client) SubscribeToolUse(ctx context.Context, toolUseID string) (*Subscription, error) {
+	d, err := c.dispatch()
+	if err != nil {
+		return nil, err
+	}
+
+	match := func(msg Message) bool {
+		return MessageParentToolUseID(msg) == toolUseID
+	}
+	var until func(Message) bool
+	if toolUseID != "" {
+		until = func(msg Message) bool {
+			return isToolResult(msg, toolUseID)
+		}
+	}
+	return d.observe(ctx, match, until), nil
+}
+
+// isToolResult reports whether msg ends the tool use toolUseID: the
+// tool's result in the main conversation, or the end of the turn
+func isToolResult(msg Message, toolUseID string) bool {
+	switch m := msg.(type) {
+	case *ResultMessage:
+		return true
+	case *UserMessage:
+		if m.ParentToolUseID != nil {
+			return false
+		}
+		blocks, _ := m.Content.([]ContentBlock)
+		for _, block := range blocks {
+			if result, ok := block.(*ToolResultBlock); ok && result.ToolUseID == toolUseID {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package claudesdk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// subagentMessage returns an assistant message from the given session and
+// subagent, with text as its only content
+func subagentMessage(sessionID, parentToolUseID, text string) map[string]interface{} {
+	msg := map[string]interface{}{
+		"type":       "assistant",
+		"session_id": sessionID,
+		"message": map[string]interface{}{
+			"model":   "claude",
+			"content": []interface{}{map[string]interface{}{"type": "text", "text": text}},
+		},
+	}
+	if parentToolUseID != "" {
+		msg["parent_tool_use_id"] = parentToolUseID
+	}
+	return msg
+}
+
+// toolResultMessage returns the main conversation's user message carrying
+// the result of toolUseID
+func toolResultMessage(toolUseID string) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "user",
+		"message": map[string]interface{}{
+			"role": "user",
+			"content": []interface{}{map[string]interface{}{
+				"type":        "tool_result",
+				"tool_use_id": toolUseID,
+				"content":     "done",
+			}},
+		},
+	}
+}
+
+func texts(messages []Message) []string {
+	var result []string
+	for _, msg := range messages {
+		if m, ok := msg.(*AssistantMessage); ok {
+			result = append(result, m.Content[0].(*TextBlock).Text)
+		}
+	}
+	return result
+}
+
+func TestMessageRouting(t *testing.T) {
+	t.Run("Parse", func(t *testing.T) {
+		msg, err := ParseMessage(subagentMessage("s1", "toolu_1", "hi"))
+		require.NoError(t, err)
+		assert.Equal(t, "s1", MessageSessionID(msg))
+		assert.Equal(t, "toolu_1", MessageParentToolUseID(msg))
+
+		msg, err = ParseMessage(map[string]interface{}{
+			"type":               "user",
+			"session_id":         "s2",
+			"parent_tool_use_id": nil,
+			"message":            map[string]interface{}{"role": "user", "content": "hello"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "s2", MessageSessionID(msg))
+		assert.Empty(t, MessageParentToolUseID(msg))
+
+		msg, err = ParseMessage(map[string]interface{}{"type": "system", "subtype": "init", "session_id": "s3"})
+		require.NoError(t, err)
+		assert.Equal(t, "s3", MessageSessionID(msg))
+
+		msg, err = ParseMessage(map[string]interface{}{"type": "system", "subtype": "other", "session_id": "s4"})
+		require.NoError(t, err)
+		assert.Equal(t, "s4", MessageSessionID(msg))
+
+		assert.Equal(t, "s5", MessageSessionID(&ResultMessage{SessionID: "s5"}))
+	})
+
+	t.Run("Subagents", func(t *testing.T) {
+		transport, cli := newTestTransport(t, nil)
+		client := connectedClient(t, transport)
+		ctx := context.Background()
+
+		main, err := client.SubscribeToolUse(ctx, "")
+		require.NoError(t, err)
+		task, err := client.SubscribeToolUse(ctx, "toolu_1")
+		require.NoError(t, err)
+
+		cli.send(t, subagentMessage("s1", "", "starting a task"))
+		cli.send(t, subagentMessage("s1", "toolu_1", "searching"))
+		cli.send(t, subagentMessage("s1", "toolu_2", "elsewhere"))
+		cli.send(t, subagentMessage("s1", "toolu_1", "found it"))
+		cli.send(t, toolResultMessage("toolu_1"))
+		cli.send(t, subagentMessage("s1", "", "done"))
+
+		assert.Equal(t, []string{"starting a task", "done"}, texts(receive(t, main.Messages(), 3)))
+
+		// The task's subscription ends with its tool result
+		var got []Message
+		for msg := range task.Messages() {
+			got = append(got, msg)
+		}
+		assert.Equal(t, []string{"searching", "found it"}, texts(got))
+		assert.NoError(t, task.Err())
+
+		// Observers take nothing, so a receiver still gets every message
+		messages, err := client.ReceiveMessages(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"starting a task", "searching", "elsewhere", "found it", "done"},
+			texts(receive(t, messages, 6)))
+	})
+
+	t.Run("A subagent ends with the turn", func(t *testing.T) {
+		transport, cli := newTestTransport(t, nil)
+		client := connectedClient(t, transport)
+
+		task, err := client.SubscribeToolUse(context.Background(), "toolu_1")
+		require.NoError(t, err)
+		cli.send(t, subagentMessage("s1", "toolu_1", "searching"))
+		cli.send(t, resultMessage("s1"))
+
+		var got []Message
+		for msg := range task.Messages() {
+			got = append(got, msg)
+		}
+		assert.Equal(t, []string{"searching"}, texts(got))
+		assert.NoError(t, task.Err())
+	})
+}
@@ -0,0 +1,118 @@
+package claudesdk
+
+import "fmt"
+
+// ClientState is a stage in a Client's lifecycle.
+//
+//	Idle ──Connect──▶ Connecting ──▶ Connected ──Disconnect──▶ Draining ──▶ Closed
+//	                       │              │
+//	                       ▼              ▼ CLI exits
+//	                     Failed    Failed, or Closed on a clean exit
+//
+// Connect is allowed from Idle, Closed and Failed, and starts a new CLI
+// process. Disconnect is allowed from every state except Connecting.
+type ClientState int
+
+const (
+	ClientIdle       ClientState = iota // not yet connected
+	ClientConnecting                    // starting the CLI
+	ClientConnected                     // ready for queries
+	ClientDraining                      // Disconnect is shutting the CLI down
+	ClientClosed                        // disconnected, or the CLI exited cleanly
+	ClientFailed                        // the CLI failed to start or crashed
+)
+
+func (s ClientState) String() string {
+	switch s {
+	case ClientIdle:
+		return "idle"
+	case ClientConnecting:
+		return "connecting"
+	case ClientConnected:
+		return "connected"
+	case ClientDraining:
+		return "draining"
+	case ClientClosed:
+		return "closed"
+	case ClientFailed:
+		return "failed"
+	default:
+		return fmt.Sprintf("ClientState(%d)", int(s))
+	}
+}
+
+// stateChange is a transition waiting to be reported to listeners
+type stateChange struct {
+	from, to ClientState
+}
+
+// State returns the Client's current state
+func (c *Client) State() ClientState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// OnStateChange registers fn to be called after every state transition.
+// Calls are made one at a time, in the order the transitions happened, and
+// never with the Client's lock held, so fn may call back into the Client.
+func (c *Client) OnStateChange(fn func(from, to ClientState)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, fn)
+}
+
+// setState moves to a new state; c.mu must be held. Call notify once the
+// lock is released.
+func (c *Client) setState(to ClientState) {
+	if c.state == to {
+		return
+	}
+	c.changes = append(c.changes, stateChange{from: c.state, to: to})
+	c.state = to
+}
+
+// notify reports pending transitions to listeners. If another goroutine,
+// or a listener further up the stack, is already reporting, it picks them
+// up instead, which keeps the calls in order.
+func (c *Client) notify() {
+	c.mu.Lock()
+	if c.notifying {
+		c.mu.Unlock()
+		return
+	}
+	c.notifying = true
+
+	for len(c.changes) > 0 {
+		change := c.changes[0]
+		c.changes = c.changes[1:]
+		listeners := c.listeners
+		c.mu.Unlock()
+
+		for _, fn := range listeners {
+			fn(change.from, change.to)
+		}
+
+		c.mu.Lock()
+	}
+
+	c.notifying = false
+	c.mu.Unlock()
+}
+
+// stateError reports that op is not allowed in the current state; c.mu
+// must be held
+func (c *Client) stateError(op string) *ClientStateError {
+	return &ClientStateError{
+		CLIError: CLIError{Message: fmt.Sprintf("cannot %s: client is %s", op, c.state)},
+		Op:       op,
+		State:    c.state,
+	}
+}
+
+// notConnected reports that op needs a connected Client; c.mu must be held
+func (c *Client) notConnected(op string) *ClientStateError {
+	err := c.stateError(op)
+	err.Cause = NewCLIConnectionError("Not connected. Call Connect() first.")
+	return err
+}
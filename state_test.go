@@ -0,0 +1,217 @@
+package claudesdk
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stateRecorder collects a Client's state transitions
+type stateRecorder struct {
+	mu      sync.Mutex
+	changes []string
+}
+
+func (r *stateRecorder) record(from, to ClientState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, from.String()+"->"+to.String())
+}
+
+func (r *stateRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.changes...)
+}
+
+func TestClientState(t *testing.T) {
+	idleCLI := `exec sleep 30
+`
+	options := func() *ClaudeCodeOptions {
+		options := NewClaudeCodeOptions()
+		options.ShutdownGracePeriod = 100 * time.Millisecond
+		return options
+	}
+
+	t.Run("Lifecycle", func(t *testing.T) {
+		useFakeCLI(t, idleCLI)
+		client := NewClient(options())
+		var recorder stateRecorder
+		client.OnStateChange(recorder.record)
+		assert.Equal(t, ClientIdle, client.State())
+
+		require.NoError(t, client.Connect(context.Background(), nil))
+		assert.Equal(t, ClientConnected, client.State())
+		require.NoError(t, client.Connect(context.Background(), nil))
+		require.NoError(t, client.Disconnect())
+		assert.Equal(t, ClientClosed, client.State())
+		require.NoError(t, client.Disconnect())
+
+		// Reconnecting starts a fresh session
+		require.NoError(t, client.Connect(context.Background(), nil))
+		require.NoError(t, client.Close())
+
+		assert.Equal(t, []string{
+			"idle->connecting", "connecting->connected", "connected->draining", "draining->closed",
+			"closed->connecting", "connecting->connected", "connected->draining", "draining->closed",
+		}, recorder.get())
+	})
+
+	t.Run("Not connected", func(t *testing.T) {
+		client := NewClient(nil)
+
+		err := client.Query(context.Background(), "hello", "")
+		var stateErr *ClientStateError
+		require.True(t, errors.As(err, &stateErr))
+		assert.Equal(t, ClientIdle, stateErr.State)
+		var connErr *CLIConnectionError
+		assert.True(t, errors.As(err, &connErr))
+
+		_, err = client.ReceiveMessages(context.Background())
+		assert.True(t, errors.As(err, &stateErr))
+		assert.True(t, errors.As(client.Interrupt(), &stateErr))
+		assert.True(t, errors.As(client.Wait(context.Background()), &stateErr))
+		assert.NoError(t, client.Disconnect())
+	})
+
+	t.Run("Failed connect", func(t *testing.T) {
+		useFakeCLI(t, idleCLI)
+		client := NewClient(options())
+		var recorder stateRecorder
+		client.OnStateChange(recorder.record)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(t, client.Connect(ctx, nil), context.Canceled)
+		assert.Equal(t, ClientFailed, client.State())
+		assert.ErrorIs(t, client.Wait(context.Background()), context.Canceled)
+
+		require.NoError(t, client.Connect(context.Background(), nil))
+		require.NoError(t, client.Disconnect())
+		assert.Equal(t, []string{
+			"idle->connecting", "connecting->failed",
+			"failed->connecting", "connecting->connected", "connected->draining", "draining->closed",
+		}, recorder.get())
+	})
+
+	t.Run("CLI crash", func(t *testing.T) {
+		useFakeCLI(t, `echo '{"type":"system","subtype":"init"}'
+exit 3
+`)
+		client := NewClient(options())
+		failed := make(chan struct{})
+		client.OnStateChange(func(from, to ClientState) {
+			// Listeners may call back into the client
+			if to == ClientFailed && client.State() == ClientFailed {
+				close(failed)
+			}
+		})
+
+		require.NoError(t, client.Connect(context.Background(), nil))
+		<-failed
+
+		var processErr *ProcessError
+		require.True(t, errors.As(client.Wait(context.Background()), &processErr))
+		assert.Equal(t, 3, processErr.ExitCode)
+
+		// What the CLI said before failing is still there
+		var messages []Message
+		var lastErr error
+		for msg, err := range client.ReceiveMessagesIter(context.Background()) {
+			if err != nil {
+				lastErr = err
+				break
+			}
+			messages = append(messages, msg)
+		}
+		assert.Len(t, messages, 1)
+		assert.True(t, errors.As(lastErr, &processErr))
+
+		assert.True(t, errors.As(client.Query(context.Background(), "hello", ""), new(*ClientStateError)))
+		require.NoError(t, client.Disconnect())
+		assert.Equal(t, ClientClosed, client.State())
+	})
+
+	t.Run("Skipped line", func(t *testing.T) {
+		useFakeCLI(t, `echo 'not json at all'
+echo '{"type":"result","subtype":"success","duration_ms":10,"duration_api_ms":5,"is_error":false,"num_turns":1,"session_id":"s1"}'
+exec sleep 30
+`)
+		client := NewClient(options())
+		require.NoError(t, client.Connect(context.Background(), nil))
+
+		msgs, err := client.ReceiveResponse(context.Background())
+		require.NoError(t, err)
+		receive(t, msgs, 1)
+
+		var decodeErr *JSONDecodeError
+		assert.True(t, errors.As(client.StreamErr(), &decodeErr))
+		assert.Equal(t, ClientConnected, client.State())
+
+		// Still reported once the session is over
+		require.NoError(t, client.Disconnect())
+		assert.True(t, errors.As(client.StreamErr(), &decodeErr))
+	})
+
+	t.Run("Disconnect while streaming a prompt", func(t *testing.T) {
+		useFakeCLI(t, `exec cat >/dev/null
+`)
+		client := NewClient(options())
+
+		prompt := make(chan map[string]interface{})
+		require.NoError(t, client.Connect(context.Background(), prompt))
+
+		stop := make(chan struct{})
+		fed := make(chan struct{})
+		go func() {
+			defer close(fed)
+			for {
+				msg := map[string]interface{}{"type": "user", "message": map[string]interface{}{"role": "user", "content": "hi"}}
+				select {
+				case prompt <- msg:
+				case <-stop:
+					close(prompt)
+					return
+				}
+			}
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+		require.NoError(t, client.Disconnect())
+		close(stop)
+		<-fed
+	})
+
+	t.Run("Concurrent use", func(t *testing.T) {
+		useFakeCLI(t, idleCLI)
+		client := NewClient(options())
+		require.NoError(t, client.Connect(context.Background(), nil))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 20; j++ {
+					// Fails with *ClientStateError once disconnected
+					client.Query(context.Background(), "hello", "")
+					client.State()
+				}
+			}()
+		}
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, client.Disconnect())
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, ClientClosed, client.State())
+	})
+}
@@ -0,0 +1,161 @@
+package claudesdk
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// PartialMessageAssembler builds content blocks from StreamEvent deltas so a
+// UI can render an assistant turn while it is being generated.
+//
+// Example:
+//
+//	assembler := NewPartialMessageAssembler()
+//	for msg, err := range client.ReceiveResponseIter(ctx) {
+//	    if event, ok := msg.(*StreamEvent); ok {
+//	        if done := assembler.Add(event); done != nil {
+//	            render(done.Content)
+//	        } else {
+//	            render(assembler.Blocks())
+//	        }
+//	    }
+//	}
+type PartialMessageAssembler struct {
+	model  string
+	blocks map[int]*partialBlock
+}
+
+// partialBlock accumulates the deltas of one content block
+type partialBlock struct {
+	start     map[string]interface{} // content_block from content_block_start
+	text      strings.Builder
+	thinking  strings.Builder
+	json      strings.Builder
+	signature strings.Builder
+}
+
+// NewPartialMessageAssembler creates an empty assembler
+func NewPartialMessageAssembler() *PartialMessageAssembler {
+	return &PartialMessageAssembler{
+		blocks: make(map[int]*partialBlock),
+	}
+}
+
+// Add applies a stream event. It returns the completed AssistantMessage when
+// the event is message_stop, and nil otherwise. After message_stop the
+// assembler is reset for the next message.
+func (a *PartialMessageAssembler) Add(event *StreamEvent) *AssistantMessage {
+	switch event.EventType {
+	case "message_start":
+		a.blocks = make(map[int]*partialBlock)
+		if message, ok := event.Event["message"].(map[string]interface{}); ok {
+			a.model, _ = message["model"].(string)
+		}
+
+	case "content_block_start":
+		start, _ := event.Event["content_block"].(map[string]interface{})
+		if start == nil {
+			start = map[string]interface{}{"type": "text"}
+		}
+		block := &partialBlock{start: start}
+		// Some blocks carry initial content in the start event
+		if text, ok := start["text"].(string); ok {
+			block.text.WriteString(text)
+		}
+		if thinking, ok := start["thinking"].(string); ok {
+			block.thinking.WriteString(thinking)
+		}
+		a.blocks[event.Index] = block
+
+	case "content_block_delta":
+		block, ok := a.blocks[event.Index]
+		if !ok || event.Delta == nil {
+			break
+		}
+		switch event.Delta.Type {
+		case "text_delta":
+			block.text.WriteString(event.Delta.Text)
+		case "thinking_delta":
+			block.thinking.WriteString(event.Delta.Thinking)
+		case "input_json_delta":
+			block.json.WriteString(event.Delta.PartialJSON)
+		case "signature_delta":
+			block.signature.WriteString(event.Delta.Signature)
+		}
+
+	case "message_stop":
+		msg := &AssistantMessage{
+			Content: a.Blocks(),
+			Model:   a.model,
+		}
+		a.blocks = make(map[int]*partialBlock)
+		return msg
+	}
+
+	return nil
+}
+
+// Blocks returns the content blocks assembled so far, in index order. Tool
+// inputs are only filled in once their JSON is complete.
+func (a *PartialMessageAssembler) Blocks() []ContentBlock {
+	indexes := make([]int, 0, len(a.blocks))
+	for index := range a.blocks {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	blocks := make([]ContentBlock, 0, len(indexes))
+	for _, index := range indexes {
+		if block, err := parseContentBlock(a.blocks[index].snapshot()); err == nil {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// Text returns the concatenated text of the blocks assembled so far
+func (a *PartialMessageAssembler) Text() string {
+	var sb strings.Builder
+	for _, block := range a.Blocks() {
+		if text, ok := block.(*TextBlock); ok {
+			sb.WriteString(text.Text)
+		}
+	}
+	return sb.String()
+}
+
+// Thinking returns the concatenated thinking of the blocks assembled so far
+func (a *PartialMessageAssembler) Thinking() string {
+	var sb strings.Builder
+	for _, block := range a.Blocks() {
+		if thinking, ok := block.(*ThinkingBlock); ok {
+			sb.WriteString(thinking.Thinking)
+		}
+	}
+	return sb.String()
+}
+
+// snapshot renders the block's current state in content block wire format
+func (b *partialBlock) snapshot() map[string]interface{} {
+	data := make(map[string]interface{}, len(b.start)+1)
+	for k, v := range b.start {
+		data[k] = v
+	}
+
+	switch data["type"] {
+	case "text":
+		data["text"] = b.text.String()
+	case "thinking":
+		data["thinking"] = b.thinking.String()
+		data["signature"] = b.signature.String()
+	case "tool_use", "server_tool_use":
+		input := map[string]interface{}{}
+		if b.json.Len() > 0 {
+			json.Unmarshal([]byte(b.json.String()), &input)
+		}
+		data["input"] = input
+	}
+
+	return data
+}
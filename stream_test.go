@@ -0,0 +1,99 @@
+package claudesdk
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func streamEvent(t *testing.T, event map[string]interface{}) *StreamEvent {
+	t.Helper()
+
+	msg, err := ParseMessage(map[string]interface{}{
+		"type":       "stream_event",
+		"uuid":       "evt-1",
+		"session_id": "session-1",
+		"event":      event,
+	})
+	require.NoError(t, err)
+	return msg.(*StreamEvent)
+}
+
+func TestParseStreamEvent(t *testing.T) {
+	event := streamEvent(t, map[string]interface{}{
+		"type":  "content_block_delta",
+		"index": float64(1),
+		"delta": map[string]interface{}{
+			"type": "text_delta",
+			"text": "Hel",
+		},
+	})
+
+	assert.Equal(t, "evt-1", event.UUID)
+	assert.Equal(t, "session-1", event.SessionID)
+	assert.Equal(t, "content_block_delta", event.EventType)
+	assert.Equal(t, 1, event.Index)
+	require.NotNil(t, event.Delta)
+	assert.Equal(t, "text_delta", event.Delta.Type)
+	assert.Equal(t, "Hel", event.Delta.Text)
+}
+
+func TestPartialMessageAssembler(t *testing.T) {
+	events := []map[string]interface{}{
+		{"type": "message_start", "message": map[string]interface{}{"model": "claude-sonnet-4-5"}},
+		{"type": "content_block_start", "index": 0, "content_block": map[string]interface{}{"type": "thinking", "thinking": ""}},
+		{"type": "content_block_delta", "index": 0, "delta": map[string]interface{}{"type": "thinking_delta", "thinking": "Let me "}},
+		{"type": "content_block_delta", "index": 0, "delta": map[string]interface{}{"type": "thinking_delta", "thinking": "check."}},
+		{"type": "content_block_delta", "index": 0, "delta": map[string]interface{}{"type": "signature_delta", "signature": "sig"}},
+		{"type": "content_block_stop", "index": 0},
+		{"type": "content_block_start", "index": 1, "content_block": map[string]interface{}{"type": "text", "text": ""}},
+		{"type": "content_block_delta", "index": 1, "delta": map[string]interface{}{"type": "text_delta", "text": "Reading "}},
+		{"type": "content_block_delta", "index": 1, "delta": map[string]interface{}{"type": "text_delta", "text": "the file."}},
+		{"type": "content_block_stop", "index": 1},
+		{"type": "content_block_start", "index": 2, "content_block": map[string]interface{}{"type": "tool_use", "id": "toolu_1", "name": "Read", "input": map[string]interface{}{}}},
+		{"type": "content_block_delta", "index": 2, "delta": map[string]interface{}{"type": "input_json_delta", "partial_json": `{"file_path":`}},
+	}
+
+	assembler := NewPartialMessageAssembler()
+	for _, event := range events {
+		assert.Nil(t, assembler.Add(streamEvent(t, event)))
+	}
+
+	// Mid-stream snapshot: the tool input is still incomplete
+	blocks := assembler.Blocks()
+	require.Len(t, blocks, 3)
+	assert.Equal(t, "Reading the file.", assembler.Text())
+	assert.Empty(t, blocks[2].(*ToolUseBlock).Input)
+
+	for _, event := range []map[string]interface{}{
+		{"type": "content_block_delta", "index": 2, "delta": map[string]interface{}{"type": "input_json_delta", "partial_json": ` "/tmp/a.txt"}`}},
+		{"type": "content_block_stop", "index": 2},
+		{"type": "message_delta", "delta": map[string]interface{}{"stop_reason": "tool_use"}},
+	} {
+		assert.Nil(t, assembler.Add(streamEvent(t, event)))
+	}
+
+	msg := assembler.Add(streamEvent(t, map[string]interface{}{"type": "message_stop"}))
+	require.NotNil(t, msg)
+	assert.Equal(t, "claude-sonnet-4-5", msg.Model)
+	require.Len(t, msg.Content, 3)
+
+	thinking := msg.Content[0].(*ThinkingBlock)
+	assert.Equal(t, "Let me check.", thinking.Thinking)
+	assert.Equal(t, "sig", thinking.Signature)
+	assert.Equal(t, "Reading the file.", msg.Content[1].(*TextBlock).Text)
+	tool := msg.Content[2].(*ToolUseBlock)
+	assert.Equal(t, "Read", tool.Name)
+	assert.Equal(t, "/tmp/a.txt", tool.Input["file_path"])
+
+	// Reset for the next message
+	assert.Empty(t, assembler.Blocks())
+}
+
+func TestIncludePartialMessagesFlag(t *testing.T) {
+	transport, err := NewSubprocessCLITransport("hello", &ClaudeCodeOptions{IncludePartialMessages: true}, "claude", true)
+	require.NoError(t, err)
+	assert.Contains(t, strings.Join(transport.buildCommand(), " "), "--include-partial-messages")
+}
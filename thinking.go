@@ -0,0 +1,104 @@
+package claudesdk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ThinkingType selects how extended thinking is configured
+type ThinkingType string
+
+const (
+	// ThinkingEnabled gives the model a fixed thinking budget
+	ThinkingEnabled ThinkingType = "enabled"
+	// ThinkingDisabled turns extended thinking off
+	ThinkingDisabled ThinkingType = "disabled"
+	// ThinkingAdaptive leaves the budget to the CLI, which decides per
+	// prompt how much to think. No --max-thinking-tokens is sent, even if
+	// MaxThinkingTokens is set.
+	ThinkingAdaptive ThinkingType = "adaptive"
+)
+
+// MinThinkingBudgetTokens is the smallest thinking budget the API accepts
+const MinThinkingBudgetTokens = 1024
+
+// ThinkingConfig configures extended thinking. It takes precedence over
+// ClaudeCodeOptions.MaxThinkingTokens.
+type ThinkingConfig struct {
+	Type ThinkingType
+	// BudgetTokens is the thinking budget for ThinkingEnabled
+	BudgetTokens int
+}
+
+// ThinkingBudget returns a config enabling thinking with the given budget
+func ThinkingBudget(tokens int) *ThinkingConfig {
+	return &ThinkingConfig{Type: ThinkingEnabled, BudgetTokens: tokens}
+}
+
+// validateThinking checks the thinking options for values the CLI would reject
+func validateThinking(options *ClaudeCodeOptions) error {
+	if options.MaxThinkingTokens != 0 && options.MaxThinkingTokens < MinThinkingBudgetTokens {
+		return fmt.Errorf("MaxThinkingTokens must be 0 or at least %d, got %d",
+			MinThinkingBudgetTokens, options.MaxThinkingTokens)
+	}
+
+	if options.Thinking == nil {
+		return nil
+	}
+
+	switch options.Thinking.Type {
+	case ThinkingEnabled:
+		if options.Thinking.BudgetTokens < MinThinkingBudgetTokens {
+			return fmt.Errorf("thinking budget must be at least %d tokens, got %d",
+				MinThinkingBudgetTokens, options.Thinking.BudgetTokens)
+		}
+	case ThinkingDisabled, ThinkingAdaptive:
+	default:
+		return fmt.Errorf("invalid thinking type: %q", options.Thinking.Type)
+	}
+
+	return nil
+}
+
+// thinkingTokens resolves the value for --max-thinking-tokens. The second
+// result is false when the flag should be omitted.
+func thinkingTokens(options *ClaudeCodeOptions) (int, bool) {
+	if options.Thinking != nil {
+		switch options.Thinking.Type {
+		case ThinkingEnabled:
+			return options.Thinking.BudgetTokens, true
+		case ThinkingDisabled:
+			return 0, true
+		case ThinkingAdaptive:
+			return 0, false
+		}
+	}
+
+	if options.MaxThinkingTokens > 0 {
+		return options.MaxThinkingTokens, true
+	}
+	return 0, false
+}
+
+// Text returns the concatenated text blocks of the message
+func (m *AssistantMessage) Text() string {
+	var sb strings.Builder
+	for _, block := range m.Content {
+		if text, ok := block.(*TextBlock); ok {
+			sb.WriteString(text.Text)
+		}
+	}
+	return sb.String()
+}
+
+// Thinking returns the concatenated thinking blocks of the message.
+// Redacted thinking is not included.
+func (m *AssistantMessage) Thinking() string {
+	var sb strings.Builder
+	for _, block := range m.Content {
+		if thinking, ok := block.(*ThinkingBlock); ok {
+			sb.WriteString(thinking.Thinking)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,95 @@
+package claudesdk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flagValue returns the value following flag in args, if present
+func flagValue(args []string, flag string) (string, bool) {
+	for i, arg := range args {
+		if arg == flag && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+func TestThinkingFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *ClaudeCodeOptions
+		want    string
+		present bool
+	}{
+		{"Default options", NewClaudeCodeOptions(), "8000", true},
+		{"Unset", &ClaudeCodeOptions{}, "", false},
+		{"MaxThinkingTokens", &ClaudeCodeOptions{MaxThinkingTokens: 4000}, "4000", true},
+		{"Enabled overrides max", &ClaudeCodeOptions{MaxThinkingTokens: 4000, Thinking: ThinkingBudget(16000)}, "16000", true},
+		{"Disabled", &ClaudeCodeOptions{MaxThinkingTokens: 4000, Thinking: &ThinkingConfig{Type: ThinkingDisabled}}, "0", true},
+		{"Adaptive leaves it to the CLI", &ClaudeCodeOptions{MaxThinkingTokens: 12000, Thinking: &ThinkingConfig{Type: ThinkingAdaptive}}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := NewSubprocessCLITransport("hello", tt.options, "claude", true)
+			require.NoError(t, err)
+
+			value, ok := flagValue(transport.buildCommand(), "--max-thinking-tokens")
+			assert.Equal(t, tt.present, ok)
+			assert.Equal(t, tt.want, value)
+		})
+	}
+}
+
+func TestThinkingValidation(t *testing.T) {
+	invalid := []*ClaudeCodeOptions{
+		{MaxThinkingTokens: -1},
+		{MaxThinkingTokens: 1023},
+		{Thinking: ThinkingBudget(100)},
+		{Thinking: &ThinkingConfig{Type: "sometimes"}},
+	}
+
+	for _, options := range invalid {
+		_, err := NewSubprocessCLITransport("hello", options, "claude", true)
+		assert.Error(t, err)
+	}
+}
+
+func TestThinkingContent(t *testing.T) {
+	msg, err := ParseMessageFromJSON([]byte(`{
+		"type": "assistant",
+		"message": {
+			"model": "claude-sonnet-4-5",
+			"content": [
+				{"type": "thinking", "thinking": "Two plus two..."},
+				{"type": "redacted_thinking", "data": "opaque"},
+				{"type": "text", "text": "4"}
+			]
+		}
+	}`))
+	require.NoError(t, err)
+
+	assistantMsg := msg.(*AssistantMessage)
+	assert.Equal(t, "Two plus two...", assistantMsg.Thinking())
+	assert.Equal(t, "4", assistantMsg.Text())
+
+	// The same content assembled from partial events
+	assembler := NewPartialMessageAssembler()
+	for _, event := range []map[string]interface{}{
+		{"type": "content_block_start", "index": 0, "content_block": map[string]interface{}{"type": "thinking", "thinking": ""}},
+		{"type": "content_block_delta", "index": 0, "delta": map[string]interface{}{"type": "thinking_delta", "thinking": "Two plus two..."}},
+		{"type": "content_block_start", "index": 1, "content_block": map[string]interface{}{"type": "redacted_thinking", "data": "opaque"}},
+		{"type": "content_block_start", "index": 2, "content_block": map[string]interface{}{"type": "text", "text": "4"}},
+	} {
+		assembler.Add(streamEvent(t, event))
+	}
+	assert.Equal(t, "Two plus two...", assembler.Thinking())
+	assert.IsType(t, &RedactedThinkingBlock{}, assembler.Blocks()[1])
+
+	done := assembler.Add(streamEvent(t, map[string]interface{}{"type": "message_stop"}))
+	assert.Equal(t, assistantMsg.Thinking(), done.Thinking())
+	assert.Equal(t, assistantMsg.Text(), done.Text())
+}
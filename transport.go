@@ -1,18 +1,25 @@
 package claudesdk
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
-const maxBufferSize = 1024 * 1024 // 1MB buffer limit
+// DefaultShutdownGracePeriod is how long each shutdown step waits for the
+// CLI to exit before escalating, unless ShutdownGracePeriod is set
+const DefaultShutdownGracePeriod = 5 * time.Second
 
 // SubprocessCLITransport implements Transport using Claude Code CLI subprocess
 type SubprocessCLITransport struct {
@@ -20,6 +27,7 @@ type SubprocessCLITransport struct {
 	isStreaming             bool
 	options                 *ClaudeCodeOptions
 	cliPath                 string
+	entrypoint              string // reported to the CLI as CLAUDE_CODE_ENTRYPOINT
 	cwd                     string
 	closeStdinAfterPrompt   bool
 
@@ -30,12 +38,24 @@ type SubprocessCLITransport struct {
 	stderrFile  *os.File
 	
 	msgChan     chan MessageData
-	errChan     chan error
-	doneChan    chan struct{}
+	doneChan    chan struct{} // closed once the CLI has exited and stdout is drained
+	stopChan    chan struct{} // closed by Disconnect to stop the reader
 	
 	mu          sync.Mutex
 	connected   bool
-	requestCounter int
+	closing     atomic.Bool // set by Disconnect so the forced exit isn't reported as a failure
+	ctx         context.Context // bounds the subprocess lifetime
+	stopOnce    sync.Once
+
+	errMu       sync.Mutex
+	exitErr     error // fatal error: read failure or abnormal exit
+	streamErr   error // first skipped line; doesn't end the session
+
+	writeMu     sync.Mutex // serializes writes to stdin
+	control     *controlProtocol
+	results     atomic.Int64  // result messages received
+	resultSeen  chan struct{} // signalled after each result message; capacity 1
+	hooks       *hookRegistry
 }
 
 // NewSubprocessCLITransport creates a new subprocess transport
@@ -48,16 +68,33 @@ func NewSubprocessCLITransport(prompt interface{}, options *ClaudeCodeOptions, c
 		prompt:                prompt,
 		options:              options,
 		cliPath:              cliPath,
+		entrypoint:           "sdk-go",
 		closeStdinAfterPrompt: closeStdinAfterPrompt,
 		msgChan:              make(chan MessageData, 100),
-		errChan:              make(chan error, 1),
 		doneChan:             make(chan struct{}),
+		stopChan:             make(chan struct{}),
+		resultSeen:           make(chan struct{}, 1),
+	}
+	t.control = newControlProtocol(t.writeJSON, t.doneChan)
+
+	if err := validateThinking(options); err != nil {
+		return nil, err
+	}
+
+	if err := t.registerOptionHandlers(); err != nil {
+		return nil, err
 	}
 
 	// Determine if streaming based on prompt type
 	switch p := prompt.(type) {
 	case string:
 		t.isStreaming = false
+		// Control requests from the CLI need stdin, so send the prompt as
+		// a single streamed message instead of using --print
+		if t.control.hasHandlers() {
+			t.prompt = singleMessagePrompt(p)
+			t.isStreaming = true
+		}
 	case chan map[string]interface{}:
 		t.isStreaming = true
 	case <-chan map[string]interface{}:
@@ -81,6 +118,44 @@ func NewSubprocessCLITransport(prompt interface{}, options *ClaudeCodeOptions, c
 	return t, nil
 }
 
+// registerOptionHandlers installs control request handlers for the
+// callbacks configured on the options
+func (t *SubprocessCLITransport) registerOptionHandlers() error {
+	if t.options.CanUseTool != nil {
+		if t.options.PermissionPromptToolName != nil {
+			return fmt.Errorf("CanUseTool cannot be used together with PermissionPromptToolName")
+		}
+		t.control.register("can_use_tool", canUseToolHandler(t.options.CanUseTool))
+	}
+
+	if len(t.options.Hooks) > 0 {
+		t.hooks = newHookRegistry(t.options.Hooks)
+		t.control.register("hook_callback", t.hooks.handler())
+	}
+
+	if servers := sdkMCPServers(t.options); len(servers) > 0 {
+		t.control.register("mcp_message", mcpMessageHandler(servers))
+	}
+
+	return nil
+}
+
+// singleMessagePrompt wraps a string prompt as a one-message stream
+func singleMessagePrompt(prompt string) <-chan map[string]interface{} {
+	ch := make(chan map[string]interface{}, 1)
+	ch <- map[string]interface{}{
+		"type": "user",
+		"message": map[string]interface{}{
+			"role":    "user",
+			"content": prompt,
+		},
+		"parent_tool_use_id": nil,
+		"session_id":         "default",
+	}
+	close(ch)
+	return ch
+}
+
 func (t *SubprocessCLITransport) findCLI() (string, error) {
 	// Check PATH first
 	if path, err := exec.LookPath("claude"); err == nil {
@@ -104,13 +179,13 @@ func (t *SubprocessCLITransport) findCLI() (string, error) {
 
 	// Check if Node is installed
 	if _, err := exec.LookPath("node"); err != nil {
-		return "", fmt.Errorf("Claude Code requires Node.js, which is not installed.\n\n" +
+		return "", NewCLINotFoundError("Claude Code requires Node.js, which is not installed.\n\n" +
 			"Install Node.js from: https://nodejs.org/\n" +
 			"\nAfter installing Node.js, install Claude Code:\n" +
 			"  npm install -g @anthropic-ai/claude-code")
 	}
 
-	return "", fmt.Errorf("Claude Code not found. Install with:\n" +
+	return "", NewCLINotFoundError("Claude Code not found. Install with:\n" +
 		"  npm install -g @anthropic-ai/claude-code\n" +
 		"\nIf already installed locally, try:\n" +
 		"  export PATH=\"$HOME/node_modules/.bin:$PATH\"\n" +
@@ -144,8 +219,15 @@ func (t *SubprocessCLITransport) buildCommand() []string {
 		cmd = append(cmd, "--model", *t.options.Model)
 	}
 
+	if tokens, ok := thinkingTokens(t.options); ok {
+		cmd = append(cmd, "--max-thinking-tokens", fmt.Sprint(tokens))
+	}
+
 	if t.options.PermissionPromptToolName != nil {
 		cmd = append(cmd, "--permission-prompt-tool", *t.options.PermissionPromptToolName)
+	} else if t.options.CanUseTool != nil {
+		// Route permission prompts to us over the control protocol
+		cmd = append(cmd, "--permission-prompt-tool", "stdio")
 	}
 
 	if t.options.PermissionMode != nil {
@@ -168,6 +250,10 @@ func (t *SubprocessCLITransport) buildCommand() []string {
 		cmd = append(cmd, "--add-dir", dir)
 	}
 
+	if t.options.IncludePartialMessages {
+		cmd = append(cmd, "--include-partial-messages")
+	}
+
 	// Handle MCP servers
 	if len(t.options.MCPServers) > 0 {
 		mcpConfig := map[string]interface{}{
@@ -202,13 +288,88 @@ func (t *SubprocessCLITransport) buildCommand() []string {
 
 // Connect starts the subprocess
 func (t *SubprocessCLITransport) Connect() error {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+	return t.ConnectContext(context.Background())
+}
+
+// ConnectContext starts the subprocess and ties its lifetime to ctx. When
+// ctx is cancelled the CLI is sent SIGTERM, then killed if it has not
+// exited within the grace period, and Err reports the context's error.
+func (t *SubprocessCLITransport) ConnectContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
+	t.mu.Lock()
 	if t.connected {
+		t.mu.Unlock()
 		return nil
 	}
+	t.ctx = ctx
+	err := t.start()
+	if err != nil && t.stderrFile != nil {
+		t.stderrFile.Close()
+		os.Remove(t.stderrFile.Name())
+		t.stderrFile = nil
+	}
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if ctx.Done() != nil {
+		go t.watchContext()
+	}
+
+	// Register hooks with the CLI before any prompt is sent
+	if t.hooks != nil {
+		if err := t.initialize(ctx); err != nil {
+			t.Disconnect()
+			return err
+		}
+	}
+
+	// Start streaming input if in streaming mode
+	if t.isStreaming {
+		go t.streamInput()
+	}
+
+	return nil
+}
+
+// initialize performs the control protocol handshake that registers hook callbacks
+func (t *SubprocessCLITransport) initialize(ctx context.Context) error {
+	_, err := t.control.send(ctx, map[string]interface{}{
+		"subtype": "initialize",
+		"hooks":   t.hooks.config,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize control protocol: %w", err)
+	}
+	return nil
+}
+
+// environ builds the subprocess environment. Later entries win, so Env
+// can override both the inherited variables and the entrypoint.
+func (t *SubprocessCLITransport) environ() []string {
+	var env []string
+	if !t.options.ClearEnv {
+		env = append(env, os.Environ()...)
+	}
+	env = append(env, "CLAUDE_CODE_ENTRYPOINT="+t.entrypoint)
+
+	keys := make([]string, 0, len(t.options.Env))
+	for key := range t.options.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		env = append(env, key+"="+t.options.Env[key])
+	}
+	return env
+}
 
+// start launches the subprocess and the stdout reader; t.mu must be held
+func (t *SubprocessCLITransport) start() error {
 	// Create temp file for stderr
 	stderrFile, err := os.CreateTemp("", "claude_stderr_*.log")
 	if err != nil {
@@ -220,8 +381,11 @@ func (t *SubprocessCLITransport) Connect() error {
 	cmdArgs := t.buildCommand()
 	t.cmd = exec.Command(cmdArgs[0], cmdArgs[1:]...)
 	t.cmd.Dir = t.cwd
-	t.cmd.Env = append(os.Environ(), "CLAUDE_CODE_ENTRYPOINT=sdk-go")
+	t.cmd.Env = t.environ()
 	t.cmd.Stderr = stderrFile
+	if !t.options.DisableProcessGroup {
+		setProcessGroup(t.cmd)
+	}
 
 	// Set up pipes
 	if t.isStreaming {
@@ -238,7 +402,14 @@ func (t *SubprocessCLITransport) Connect() error {
 
 	// Start the process
 	if err := t.cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start Claude Code: %w", err)
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+			return &CLINotFoundError{
+				CLIError: CLIError{Message: "Claude Code not found at: " + t.cliPath, Cause: err},
+			}
+		}
+		return &CLIConnectionError{
+			CLIError: CLIError{Message: "failed to start Claude Code", Cause: err},
+		}
 	}
 
 	t.connected = true
@@ -246,161 +417,383 @@ func (t *SubprocessCLITransport) Connect() error {
 	// Start reading stdout
 	go t.readMessages()
 
-	// Start streaming input if in streaming mode
-	if t.isStreaming {
-		go t.streamInput()
-	}
-
 	return nil
 }
 
 func (t *SubprocessCLITransport) streamInput() {
+	var turns int64 // user messages written, each answered by one result
+
 	defer func() {
-		if t.closeStdinAfterPrompt && t.stdin != nil {
-			t.stdin.Close()
+		if t.closeStdinAfterPrompt {
+			// The CLI may still send control requests while it works on
+			// the prompt, so keep stdin open until every turn is done
+			if t.control.hasHandlers() {
+				t.waitForResults(turns)
+			}
+			t.writeMu.Lock()
+			if t.stdin != nil {
+				t.stdin.Close()
+			}
+			t.writeMu.Unlock()
 		}
 	}()
 
+	// writeJSON fails once stdin is gone, which ends the loop
 	switch prompt := t.prompt.(type) {
 	case chan map[string]interface{}:
 		for msg := range prompt {
-			if t.stdin == nil {
+			if err := t.writeJSON(msg); err != nil {
 				break
 			}
-			data, err := json.Marshal(msg)
-			if err != nil {
-				continue
-			}
-			if _, err := fmt.Fprintln(t.stdin, string(data)); err != nil {
-				break
+			if msg["type"] == "user" {
+				turns++
 			}
 		}
 	case <-chan map[string]interface{}:
 		for msg := range prompt {
-			if t.stdin == nil {
+			if err := t.writeJSON(msg); err != nil {
 				break
 			}
-			data, err := json.Marshal(msg)
-			if err != nil {
-				continue
-			}
-			if _, err := fmt.Fprintln(t.stdin, string(data)); err != nil {
-				break
+			if msg["type"] == "user" {
+				turns++
 			}
 		}
 	}
 }
 
+// waitForResults blocks until n result messages have arrived or the CLI
+// has exited
+func (t *SubprocessCLITransport) waitForResults(n int64) {
+	for t.results.Load() < n {
+		select {
+		case <-t.resultSeen:
+		case <-t.doneChan:
+			return
+		}
+	}
+}
+
+// writeJSON writes a single JSON line to the CLI's stdin
+func (t *SubprocessCLITransport) writeJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if t.stdin == nil {
+		return fmt.Errorf("stdin not available - stream may have ended")
+	}
+
+	_, err = fmt.Fprintln(t.stdin, string(data))
+	return err
+}
+
 func (t *SubprocessCLITransport) readMessages() {
 	defer close(t.msgChan)
 	defer close(t.doneChan)
 
-	scanner := bufio.NewScanner(t.stdout)
-	scanner.Buffer(make([]byte, maxBufferSize), maxBufferSize)
+	maxSize := t.options.MaxMessageSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxMessageSize
+	}
+	decoder := newMessageDecoder(t.stdout, maxSize)
 
-	jsonBuffer := ""
+	for {
+		raw, err := decoder.next()
+		if err != nil {
+			var decodeErr *JSONDecodeError
+			var tooLarge *MessageTooLargeError
+			if errors.As(err, &decodeErr) || errors.As(err, &tooLarge) {
+				// The decoder has skipped to the next line
+				t.setStreamErr(err)
+				continue
+			}
+			if err != io.EOF && !t.closing.Load() {
+				t.setExitErr(&CLIConnectionError{
+					CLIError: CLIError{Message: "failed to read CLI output", Cause: err},
+				})
+				// The stream can't be resynchronized, so stop the CLI rather
+				// than leave it blocked writing to a pipe nobody reads
+				if t.cmd != nil && t.cmd.Process != nil {
+					t.signal(syscall.SIGKILL)
+				}
+			}
+			break
+		}
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
+		data, err := decodeEnvelope(raw)
+		if err != nil {
+			t.setStreamErr(NewJSONDecodeError(fmt.Sprintf("failed to decode CLI output: %.200s", raw), err))
 			continue
 		}
 
-		jsonBuffer += line
+		// Route control protocol traffic away from the message stream
+		switch data.Type {
+		case "control_response":
+			t.control.handleResponse(raw)
+			continue
+		case "control_request":
+			go t.control.handleRequest(raw)
+			continue
+		case "control_cancel_request":
+			t.control.handleCancel(raw)
+			continue
+		case "result":
+			t.results.Add(1)
+			select {
+			case t.resultSeen <- struct{}{}:
+			default:
+			}
+		}
 
-		if len(jsonBuffer) > maxBufferSize {
-			t.errChan <- fmt.Errorf("JSON message exceeded maximum buffer size")
-			jsonBuffer = ""
+		// Once Disconnect has begun nothing more is delivered, but stdout
+		// keeps being drained so the CLI is never blocked writing while it
+		// finishes up. stopChan is checked first so a message read after
+		// that point is always dropped, even if the channel has room.
+		select {
+		case <-t.stopChan:
 			continue
+		default:
 		}
+		select {
+		case t.msgChan <- data:
+		case <-t.stopChan:
+		}
+	}
 
-		var data MessageData
-		if err := json.Unmarshal([]byte(jsonBuffer), &data); err == nil {
-			jsonBuffer = ""
-			
-			// Skip control responses
-			if data.Type == "control_response" {
-				continue
-			}
+	t.wait()
+}
 
-			select {
-			case t.msgChan <- data:
-			case <-t.doneChan:
-				return
-			}
-		}
-		// If JSON parsing fails, continue accumulating
+// decodeEnvelope decodes only the fields needed to route a message. The
+// message itself is parsed once, by whoever receives it, from Raw.
+func decodeEnvelope(raw []byte) (MessageData, error) {
+	var envelope struct {
+		Type            string  `json:"type"`
+		Subtype         string  `json:"subtype"`
+		SessionID       string  `json:"session_id"`
+		ParentToolUseID *string `json:"parent_tool_use_id"`
+		UUID            string  `json:"uuid"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return MessageData{}, err
+	}
+	return MessageData{
+		Type:            envelope.Type,
+		Subtype:         envelope.Subtype,
+		SessionID:       envelope.SessionID,
+		ParentToolUseID: envelope.ParentToolUseID,
+		UUID:            envelope.UUID,
+		Raw:             raw,
+	}, nil
+}
+
+// wait reaps the process and records an abnormal exit as a ProcessError
+func (t *SubprocessCLITransport) wait() {
+	if t.cmd == nil {
+		return
 	}
 
-	if err := scanner.Err(); err != nil {
-		t.errChan <- err
+	err := t.cmd.Wait()
+	if err == nil || t.closing.Load() {
+		return
 	}
 
-	// Wait for process to complete
-	if t.cmd != nil {
-		if err := t.cmd.Wait(); err != nil {
-			// Read stderr for error details
-			if t.stderrFile != nil {
-				t.stderrFile.Seek(0, 0)
-				stderr, _ := io.ReadAll(t.stderrFile)
-				if len(stderr) > 0 {
-					t.errChan <- fmt.Errorf("command failed: %s", string(stderr))
-				} else {
-					t.errChan <- err
-				}
-			} else {
-				t.errChan <- err
-			}
-		}
+	exitCode := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+
+	stderr := ""
+	if t.stderrFile != nil {
+		t.stderrFile.Seek(0, 0)
+		data, _ := io.ReadAll(t.stderrFile)
+		stderr = strings.TrimSpace(string(data))
 	}
+
+	processErr := NewProcessError("Claude Code process failed", exitCode, stderr)
+	processErr.Cause = err
+	t.setExitErr(processErr)
 }
 
-// Disconnect terminates the subprocess
-func (t *SubprocessCLITransport) Disconnect() error {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+func (t *SubprocessCLITransport) setExitErr(err error) {
+	t.errMu.Lock()
+	defer t.errMu.Unlock()
 
-	if !t.connected {
-		return nil
+	if t.exitErr == nil {
+		t.exitErr = err
 	}
+}
 
-	// Close stdin if still open
-	if t.stdin != nil {
-		t.stdin.Close()
-		t.stdin = nil
+func (t *SubprocessCLITransport) setStreamErr(err error) {
+	t.errMu.Lock()
+	defer t.errMu.Unlock()
+
+	if t.streamErr == nil {
+		t.streamErr = err
 	}
+}
 
-	// Terminate process
-	if t.cmd != nil && t.cmd.Process != nil {
-		t.cmd.Process.Kill()
-		t.cmd.Wait()
+// Err returns the error that ended the session, or nil if the CLI exited
+// cleanly or was stopped by Disconnect. It is only meaningful once Done is
+// closed (and so the message channel is closed).
+//
+// A failed process is reported as *ProcessError carrying the exit code and
+// stderr. Skipped lines don't end the session; see StreamErr.
+func (t *SubprocessCLITransport) Err() error {
+	t.errMu.Lock()
+	defer t.errMu.Unlock()
+	return t.exitErr
+}
+
+// StreamErr returns the first line of CLI output that was skipped, as
+// *JSONDecodeError if it was malformed or *MessageTooLargeError if it was
+// over MaxMessageSize, or nil if none was
+func (t *SubprocessCLITransport) StreamErr() error {
+	t.errMu.Lock()
+	defer t.errMu.Unlock()
+	return t.streamErr
+}
+
+// Done returns a channel that is closed when the CLI has exited and all of
+// its output has been read
+func (t *SubprocessCLITransport) Done() <-chan struct{} {
+	return t.doneChan
+}
+
+// Disconnect shuts the subprocess down. It closes stdin and gives the CLI
+// the grace period to finish the current turn and exit by itself, so the
+// session is saved intact. Only then is it sent SIGTERM, and after another
+// grace period SIGKILL.
+//
+// The shutdown runs without holding t.mu, so control requests made
+// meanwhile fail straight away instead of waiting for it.
+func (t *SubprocessCLITransport) Disconnect() error {
+	t.mu.Lock()
+	if !t.connected {
+		t.mu.Unlock()
+		return nil
 	}
+	t.connected = false
+	graceful := t.ctx == nil || t.ctx.Err() == nil
+	t.mu.Unlock()
+
+	t.stop(graceful)
 
-	// Clean up stderr file
+	// Clean up stderr file; the reader has finished with it by now
+	t.mu.Lock()
 	if t.stderrFile != nil {
 		t.stderrFile.Close()
 		os.Remove(t.stderrFile.Name())
 		t.stderrFile = nil
 	}
-
-	t.connected = false
+	t.mu.Unlock()
 
 	return nil
 }
 
+// watchContext stops the CLI when the context passed to ConnectContext is
+// cancelled
+func (t *SubprocessCLITransport) watchContext() {
+	select {
+	case <-t.ctx.Done():
+		if !t.closing.Load() {
+			t.setExitErr(t.ctx.Err())
+		}
+		t.stop(false)
+	case <-t.doneChan:
+	}
+}
+
+// stop runs the shutdown sequence once and returns when the reader has
+// finished. Unless graceful is set it skips waiting for the CLI to exit on
+// its own and goes straight to SIGTERM.
+func (t *SubprocessCLITransport) stop(graceful bool) {
+	t.stopOnce.Do(func() {
+		t.closing.Store(true)
+
+		// Close stdin so the CLI sees the end of the input
+		t.writeMu.Lock()
+		if t.stdin != nil {
+			t.stdin.Close()
+			t.stdin = nil
+		}
+		t.writeMu.Unlock()
+
+		// Stop delivering messages; the reader keeps draining stdout
+		close(t.stopChan)
+
+		if t.cmd == nil || t.cmd.Process == nil {
+			t.closeStdout()
+			return
+		}
+
+		grace := t.options.ShutdownGracePeriod
+		if grace <= 0 {
+			grace = DefaultShutdownGracePeriod
+		}
+
+		exited := graceful && t.waitExit(grace)
+		if !exited {
+			exited = t.signal(syscall.SIGTERM) == nil && t.waitExit(grace)
+		}
+		if !exited {
+			t.signal(syscall.SIGKILL)
+			t.closeStdout()
+		}
+
+		// Children such as tool shells and MCP servers can outlive the
+		// CLI; take them down with it
+		if !t.options.DisableProcessGroup {
+			signalProcessGroup(t.cmd, syscall.SIGKILL)
+		}
+	})
+}
+
+// signal sends sig to the CLI, and to its children unless
+// DisableProcessGroup is set
+func (t *SubprocessCLITransport) signal(sig syscall.Signal) error {
+	if t.options.DisableProcessGroup {
+		if sig == syscall.SIGKILL {
+			return t.cmd.Process.Kill()
+		}
+		return t.cmd.Process.Signal(sig)
+	}
+	return signalProcessGroup(t.cmd, sig)
+}
+
+// closeStdout unblocks the reader even if a grandchild still holds the
+// pipe open, and waits for it to finish
+func (t *SubprocessCLITransport) closeStdout() {
+	if t.stdout != nil {
+		t.stdout.Close()
+	}
+	<-t.doneChan
+}
+
+// waitExit reports whether the CLI exited and its output was drained
+// within d
+func (t *SubprocessCLITransport) waitExit(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-t.doneChan:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
 // SendRequest sends additional messages in streaming mode
 func (t *SubprocessCLITransport) SendRequest(messages []MessageData, metadata map[string]interface{}) error {
 	if !t.isStreaming {
 		return fmt.Errorf("SendRequest only works in streaming mode")
 	}
 
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
-	if t.stdin == nil {
-		return fmt.Errorf("stdin not available - stream may have ended")
-	}
-
 	for _, msg := range messages {
 		// Ensure session_id is set
 		if msg.SessionID == "" {
@@ -411,12 +804,7 @@ func (t *SubprocessCLITransport) SendRequest(messages []MessageData, metadata ma
 			}
 		}
 
-		data, err := json.Marshal(msg)
-		if err != nil {
-			return err
-		}
-
-		if _, err := fmt.Fprintln(t.stdin, string(data)); err != nil {
+		if err := t.writeJSON(msg); err != nil {
 			return err
 		}
 	}
@@ -433,9 +821,43 @@ func (t *SubprocessCLITransport) ReceiveMessages() (<-chan MessageData, error) {
 	return t.msgChan, nil
 }
 
-// Interrupt sends an interrupt signal (not implemented for subprocess)
+// Interrupt stops the current turn by sending an interrupt control request
+// to the CLI and waiting for its acknowledgement. The session stays open,
+// so further messages can be sent once the interrupted turn has produced
+// its ResultMessage.
 func (t *SubprocessCLITransport) Interrupt() error {
-	// In Python, this sends SIGINT, but Go doesn't have a direct equivalent
-	// You could implement this if needed
-	return fmt.Errorf("interrupt not implemented for subprocess transport")
-}
\ No newline at end of file
+	if !t.isStreaming {
+		return fmt.Errorf("Interrupt only works in streaming mode")
+	}
+
+	_, err := t.SendControlRequest(context.Background(), map[string]interface{}{
+		"subtype": "interrupt",
+	})
+	return err
+}
+
+// SendControlRequest sends a control request to the CLI and waits for its
+// response. The request must include a "subtype". If ctx has no deadline,
+// DefaultControlRequestTimeout applies.
+func (t *SubprocessCLITransport) SendControlRequest(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error) {
+	if !t.isStreaming {
+		return nil, fmt.Errorf("control requests only work in streaming mode")
+	}
+
+	t.mu.Lock()
+	connected := t.connected
+	t.mu.Unlock()
+	if !connected {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	return t.control.send(ctx, request)
+}
+
+// RegisterControlHandler installs a handler for control requests of the
+// given subtype sent by the CLI (e.g. "can_use_tool", "hook_callback",
+// "mcp_message"). Passing a nil handler removes it. Requests without a
+// handler are answered with an error response.
+func (t *SubprocessCLITransport) RegisterControlHandler(subtype string, handler ControlRequestHandler) {
+	t.control.register(subtype, handler)
+}
@@ -0,0 +1,391 @@
+package claudesdk
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCLI stands in for the Claude Code subprocess by wiring a transport's
+// stdin/stdout to in-memory pipes
+type fakeCLI struct {
+	stdin  *bufio.Scanner
+	stdout *io.PipeWriter
+}
+
+// newTestTransport returns a connected streaming transport talking to a fake CLI
+func newTestTransport(t *testing.T, options *ClaudeCodeOptions) (*SubprocessCLITransport, *fakeCLI) {
+	t.Helper()
+
+	prompt := make(chan map[string]interface{})
+	close(prompt)
+
+	transport, err := NewSubprocessCLITransport(prompt, options, "claude", false)
+	require.NoError(t, err)
+
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+
+	transport.stdin = stdinWriter
+	transport.stdout = stdoutReader
+	transport.connected = true
+	go transport.readMessages()
+
+	t.Cleanup(func() {
+		stdoutWriter.Close()
+		stdinReader.Close()
+	})
+
+	return transport, &fakeCLI{
+		stdin:  bufio.NewScanner(stdinReader),
+		stdout: stdoutWriter,
+	}
+}
+
+// readLine reads the next JSON line the transport wrote to stdin
+func (f *fakeCLI) readLine(t *testing.T) map[string]interface{} {
+	t.Helper()
+
+	require.True(t, f.stdin.Scan(), "expected a line on stdin")
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(f.stdin.Bytes(), &line))
+	return line
+}
+
+// send writes a JSON line to the transport's stdout
+func (f *fakeCLI) send(t *testing.T, v interface{}) {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	_, err = fmt.Fprintln(f.stdout, string(data))
+	require.NoError(t, err)
+}
+
+func TestInterrupt(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		transport, cli := newTestTransport(t, nil)
+
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- transport.Interrupt()
+		}()
+
+		req := cli.readLine(t)
+		assert.Equal(t, "control_request", req["type"])
+		assert.Equal(t, "interrupt", req["request"].(map[string]interface{})["subtype"])
+
+		cli.send(t, map[string]interface{}{
+			"type": "control_response",
+			"response": map[string]interface{}{
+				"subtype":    "success",
+				"request_id": req["request_id"],
+			},
+		})
+
+		assert.NoError(t, <-errChan)
+	})
+
+	t.Run("Error response", func(t *testing.T) {
+		transport, cli := newTestTransport(t, nil)
+
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- transport.Interrupt()
+		}()
+
+		req := cli.readLine(t)
+		cli.send(t, map[string]interface{}{
+			"type": "control_response",
+			"response": map[string]interface{}{
+				"subtype":    "error",
+				"request_id": req["request_id"],
+				"error":      "no turn in progress",
+			},
+		})
+
+		err := <-errChan
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no turn in progress")
+	})
+
+	t.Run("Regular messages still delivered", func(t *testing.T) {
+		transport, cli := newTestTransport(t, nil)
+
+		go func() {
+			req := cli.readLine(t)
+			cli.send(t, map[string]interface{}{
+				"type": "control_response",
+				"response": map[string]interface{}{
+					"subtype":    "success",
+					"request_id": req["request_id"],
+				},
+			})
+			cli.send(t, map[string]interface{}{
+				"type":    "system",
+				"subtype": "info",
+			})
+		}()
+
+		require.NoError(t, transport.Interrupt())
+
+		msgChan, err := transport.ReceiveMessages()
+		require.NoError(t, err)
+		data := <-msgChan
+		assert.Equal(t, "system", data.Type)
+	})
+
+	t.Run("Requires streaming mode", func(t *testing.T) {
+		transport, err := NewSubprocessCLITransport("hello", nil, "claude", true)
+		require.NoError(t, err)
+		assert.Error(t, transport.Interrupt())
+	})
+}
+
+// writeFakeCLIScript writes an executable shell script standing in for the CLI
+func writeFakeCLIScript(t *testing.T, script string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "claude")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755))
+	return path
+}
+
+// collect drains the transport's message channel
+func collect(t *testing.T, transport *SubprocessCLITransport) []MessageData {
+	t.Helper()
+
+	msgChan, err := transport.ReceiveMessages()
+	require.NoError(t, err)
+
+	var messages []MessageData
+	for data := range msgChan {
+		messages = append(messages, data)
+	}
+	return messages
+}
+
+func TestTransportErrors(t *testing.T) {
+	t.Run("Process failure", func(t *testing.T) {
+		cli := writeFakeCLIScript(t, `echo '{"type":"system","subtype":"init"}'
+echo "invalid API key" >&2
+exit 3
+`)
+		transport, err := NewSubprocessCLITransport("hello", nil, cli, true)
+		require.NoError(t, err)
+		require.NoError(t, transport.Connect())
+		defer transport.Disconnect()
+
+		assert.Len(t, collect(t, transport), 1)
+		<-transport.Done()
+
+		var processErr *ProcessError
+		require.True(t, errors.As(transport.Err(), &processErr))
+		assert.Equal(t, 3, processErr.ExitCode)
+		assert.Equal(t, "invalid API key", processErr.Stderr)
+	})
+
+	t.Run("Malformed line", func(t *testing.T) {
+		cli := writeFakeCLIScript(t, `echo 'not json at all'
+echo '{"type":"system","subtype":"init"}'
+`)
+		transport, err := NewSubprocessCLITransport("hello", nil, cli, true)
+		require.NoError(t, err)
+		require.NoError(t, transport.Connect())
+		defer transport.Disconnect()
+
+		messages := collect(t, transport)
+		require.Len(t, messages, 1)
+		assert.Equal(t, "system", messages[0].Type)
+
+		assert.NoError(t, transport.Err())
+		var decodeErr *JSONDecodeError
+		assert.True(t, errors.As(transport.StreamErr(), &decodeErr))
+	})
+
+	t.Run("Message split across lines", func(t *testing.T) {
+		cli := writeFakeCLIScript(t, `echo '{"type":"system",'
+echo '"subtype":"init"}'
+`)
+		transport, err := NewSubprocessCLITransport("hello", nil, cli, true)
+		require.NoError(t, err)
+		require.NoError(t, transport.Connect())
+		defer transport.Disconnect()
+
+		assert.Len(t, collect(t, transport), 1)
+		assert.NoError(t, transport.Err())
+	})
+
+	t.Run("CLI not found", func(t *testing.T) {
+		transport, err := NewSubprocessCLITransport("hello", nil, filepath.Join(t.TempDir(), "missing"), true)
+		require.NoError(t, err)
+
+		var notFound *CLINotFoundError
+		assert.True(t, errors.As(transport.Connect(), &notFound))
+	})
+
+	t.Run("Disconnect is not a failure", func(t *testing.T) {
+		cli := writeFakeCLIScript(t, `exec sleep 30`)
+		transport, err := NewSubprocessCLITransport("hello", &ClaudeCodeOptions{ShutdownGracePeriod: 100 * time.Millisecond}, cli, true)
+		require.NoError(t, err)
+		require.NoError(t, transport.Connect())
+
+		require.NoError(t, transport.Disconnect())
+		<-transport.Done()
+		assert.NoError(t, transport.Err())
+	})
+}
+
+func TestTransportEnv(t *testing.T) {
+	cli := writeFakeCLIScript(t, `printf '{"type":"system","subtype":"%s|%s|%s"}\n' "$ACCOUNT" "$HOME" "$CLAUDE_CODE_ENTRYPOINT"
+`)
+	t.Setenv("HOME", "/home/inherited")
+
+	run := func(t *testing.T, options *ClaudeCodeOptions) string {
+		t.Helper()
+		transport, err := NewSubprocessCLITransport("hello", options, cli, true)
+		require.NoError(t, err)
+		require.NoError(t, transport.Connect())
+		defer transport.Disconnect()
+
+		messages := collect(t, transport)
+		require.Len(t, messages, 1)
+		return messages[0].Subtype
+	}
+
+	t.Run("Inherited", func(t *testing.T) {
+		got := run(t, &ClaudeCodeOptions{Env: map[string]string{"ACCOUNT": "acme"}})
+		assert.Equal(t, "acme|/home/inherited|sdk-go", got)
+	})
+
+	t.Run("Clean", func(t *testing.T) {
+		got := run(t, &ClaudeCodeOptions{Env: map[string]string{"ACCOUNT": "globex"}, ClearEnv: true})
+		assert.Equal(t, "globex||sdk-go", got)
+	})
+
+	t.Run("Override entrypoint", func(t *testing.T) {
+		got := run(t, &ClaudeCodeOptions{Env: map[string]string{"CLAUDE_CODE_ENTRYPOINT": "my-service"}})
+		assert.Equal(t, "|/home/inherited|my-service", got)
+	})
+
+	_, set := os.LookupEnv("ACCOUNT")
+	assert.False(t, set, "options must not leak into the parent environment")
+}
+
+func TestShutdown(t *testing.T) {
+	connect := func(t *testing.T, ctx context.Context, script string) (*SubprocessCLITransport, string) {
+		t.Helper()
+		marker := filepath.Join(t.TempDir(), "marker")
+		options := &ClaudeCodeOptions{
+			Env:                 map[string]string{"MARKER": marker},
+			ShutdownGracePeriod: 500 * time.Millisecond,
+		}
+		prompt := make(chan map[string]interface{})
+		t.Cleanup(func() { close(prompt) })
+
+		transport, err := NewSubprocessCLITransport(prompt, options, writeFakeCLIScript(t, script), false)
+		require.NoError(t, err)
+		require.NoError(t, transport.ConnectContext(ctx))
+		return transport, marker
+	}
+
+	t.Run("Waits for the CLI to finish", func(t *testing.T) {
+		transport, marker := connect(t, context.Background(), `cat >/dev/null
+echo '{"type":"result","subtype":"success"}'
+touch "$MARKER"
+`)
+		require.NoError(t, transport.Disconnect())
+		assert.FileExists(t, marker)
+		assert.NoError(t, transport.Err())
+	})
+
+	t.Run("Nothing is delivered after Disconnect", func(t *testing.T) {
+		transport, _ := connect(t, context.Background(), `cat >/dev/null
+echo '{"type":"result","subtype":"success"}'
+`)
+		msgChan, err := transport.ReceiveMessages()
+		require.NoError(t, err)
+
+		require.NoError(t, transport.Disconnect())
+		_, ok := <-msgChan
+		assert.False(t, ok, "the result was written after Disconnect")
+	})
+
+	t.Run("Control requests don't wait for shutdown", func(t *testing.T) {
+		transport, _ := connect(t, context.Background(), `trap '' TERM
+exec sleep 30
+`)
+		disconnected := make(chan struct{})
+		go func() {
+			transport.Disconnect()
+			close(disconnected)
+		}()
+
+		require.Eventually(t, func() bool {
+			transport.mu.Lock()
+			defer transport.mu.Unlock()
+			return !transport.connected
+		}, time.Second, 5*time.Millisecond)
+
+		start := time.Now()
+		_, err := transport.SendControlRequest(context.Background(), map[string]interface{}{"subtype": "interrupt"})
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), 100*time.Millisecond)
+		<-disconnected
+	})
+
+	t.Run("SIGTERM before SIGKILL", func(t *testing.T) {
+		transport, marker := connect(t, context.Background(), `trap 'touch "$MARKER"; exit 0' TERM
+while :; do sleep 0.05; done
+`)
+		require.NoError(t, transport.Disconnect())
+		assert.FileExists(t, marker)
+		assert.NoError(t, transport.Err())
+	})
+
+	t.Run("SIGKILL when SIGTERM is ignored", func(t *testing.T) {
+		transport, _ := connect(t, context.Background(), `trap '' TERM
+exec sleep 30
+`)
+		start := time.Now()
+		require.NoError(t, transport.Disconnect())
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("Context cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		transport, marker := connect(t, ctx, `trap 'touch "$MARKER"; exit 0' TERM
+echo '{"type":"system","subtype":"init"}'
+while :; do sleep 0.05; done
+`)
+		defer transport.Disconnect()
+
+		// Wait until the trap is installed
+		msgChan, err := transport.ReceiveMessages()
+		require.NoError(t, err)
+		<-msgChan
+
+		cancel()
+		<-transport.Done()
+		assert.FileExists(t, marker)
+		assert.ErrorIs(t, transport.Err(), context.Canceled)
+	})
+
+	t.Run("Cancelled before connect", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		transport, err := NewSubprocessCLITransport("hello", nil, writeFakeCLIScript(t, `exit 0`), true)
+		require.NoError(t, err)
+		assert.ErrorIs(t, transport.ConnectContext(ctx), context.Canceled)
+	})
+}
@@ -0,0 +1,64 @@
+package claudesdk
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Turn is the handle for one prompt sent with Client.Send. It receives
+// exactly the messages the CLI produced in answer to that prompt, ending
+// with its ResultMessage.
+type Turn struct {
+	sub    *Subscription
+	closed atomic.Bool
+}
+
+// Messages returns the turn's messages. It is closed after the turn's
+// ResultMessage, when the session ends, on Close, or when the context
+// passed to Send is done.
+func (t *Turn) Messages() <-chan Message {
+	return t.sub.Messages()
+}
+
+// Result returns the turn's ResultMessage once Messages is closed, or nil
+// if the turn ended without one.
+func (t *Turn) Result() *ResultMessage {
+	t.sub.mu.Lock()
+	defer t.sub.mu.Unlock()
+	return t.sub.result
+}
+
+// Err returns why the turn ended without a result once Messages is closed:
+// the session's error, the context's error, or a *CLIConnectionError if the
+// session ended cleanly first. It is nil after a result or Close.
+func (t *Turn) Err() error {
+	if err := t.sub.Err(); err != nil {
+		return err
+	}
+	if t.Result() == nil && !t.closed.Load() {
+		return NewCLIConnectionError("session ended before the turn completed")
+	}
+	return nil
+}
+
+// Wait discards the turn's remaining messages and returns its result.
+func (t *Turn) Wait(ctx context.Context) (*ResultMessage, error) {
+	for {
+		select {
+		case _, ok := <-t.Messages():
+			if !ok {
+				return t.Result(), t.Err()
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Close stops delivering the turn's messages and closes Messages. The rest
+// of the turn's messages are discarded rather than passed to later turns.
+// It is safe to call more than once.
+func (t *Turn) Close() {
+	t.closed.Store(true)
+	t.sub.Unsubscribe()
+}
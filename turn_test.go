@@ -0,0 +1,175 @@
+package claudesdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTurnClient returns a connected client whose prompts are read and
+// discarded by the fake CLI
+func newTurnClient(t *testing.T, options *ClaudeCodeOptions) (*Client, *fakeCLI) {
+	t.Helper()
+
+	transport, cli := newTestTransport(t, options)
+	go func() {
+		for cli.stdin.Scan() {
+		}
+	}()
+	return connectedClient(t, transport), cli
+}
+
+// drain reads a turn's messages until it ends
+func drain(turn *Turn) []Message {
+	var messages []Message
+	for msg := range turn.Messages() {
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func TestTurns(t *testing.T) {
+	t.Run("Each turn gets its own answer", func(t *testing.T) {
+		client, cli := newTurnClient(t, nil)
+		ctx := context.Background()
+
+		logger, err := client.Subscribe(ctx)
+		require.NoError(t, err)
+
+		first, err := client.Send(ctx, "What is 2 + 2?", "")
+		require.NoError(t, err)
+		second, err := client.Send(ctx, "What is 10% of 80?", "")
+		require.NoError(t, err)
+
+		cli.send(t, systemMessage("answer1"))
+		cli.send(t, resultMessage("first"))
+		cli.send(t, systemMessage("answer2"))
+		cli.send(t, resultMessage("second"))
+
+		// The second turn can be read before the first
+		assert.Equal(t, []string{"answer2", "result"}, subtypes(drain(second)))
+		require.NoError(t, second.Err())
+		assert.Equal(t, "second", second.Result().SessionID)
+
+		result, err := first.Wait(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "first", result.SessionID)
+
+		// Subscribers still see everything
+		assert.Equal(t, []string{"answer1", "result", "answer2", "result"},
+			subtypes(receive(t, logger.Messages(), 4)))
+	})
+
+	t.Run("A closed turn keeps its messages", func(t *testing.T) {
+		client, cli := newTurnClient(t, nil)
+		ctx := context.Background()
+
+		first, err := client.Send(ctx, "one", "")
+		require.NoError(t, err)
+		second, err := client.Send(ctx, "two", "")
+		require.NoError(t, err)
+		first.Close()
+		first.Close()
+		assert.NoError(t, first.Err())
+
+		cli.send(t, systemMessage("answer1"))
+		cli.send(t, resultMessage("first"))
+		cli.send(t, systemMessage("answer2"))
+		cli.send(t, resultMessage("second"))
+
+		assert.Equal(t, []string{"answer2", "result"}, subtypes(drain(second)))
+	})
+
+	t.Run("Query answers are not attributed", func(t *testing.T) {
+		client, cli := newTurnClient(t, nil)
+		ctx := context.Background()
+
+		require.NoError(t, client.Query(ctx, "one", ""))
+		turn, err := client.Send(ctx, "two", "")
+		require.NoError(t, err)
+
+		cli.send(t, systemMessage("answer1"))
+		cli.send(t, resultMessage("first"))
+		cli.send(t, systemMessage("answer2"))
+		cli.send(t, resultMessage("second"))
+
+		assert.Equal(t, []string{"answer2", "result"}, subtypes(drain(turn)))
+
+		// The Query's answer is held for the next receiver
+		response, err := client.ReceiveResponse(ctx)
+		require.NoError(t, err)
+		var got []Message
+		for msg := range response {
+			got = append(got, msg)
+		}
+		assert.Equal(t, []string{"answer1", "result"}, subtypes(got))
+	})
+
+	t.Run("A turn that falls behind is ended", func(t *testing.T) {
+		client, cli := newTurnClient(t, &ClaudeCodeOptions{MessageBufferSize: 2})
+		ctx := context.Background()
+
+		first, err := client.Send(ctx, "one", "")
+		require.NoError(t, err)
+		second, err := client.Send(ctx, "two", "")
+		require.NoError(t, err)
+
+		for i := 0; i < 10; i++ {
+			cli.send(t, systemMessage(fmt.Sprint(i)))
+		}
+		cli.send(t, resultMessage("first"))
+		cli.send(t, systemMessage("answer2"))
+		cli.send(t, resultMessage("second"))
+
+		// The rest of the first answer isn't passed on to the second turn
+		assert.Equal(t, []string{"answer2", "result"}, subtypes(drain(second)))
+
+		assert.Contains(t, [][]string{{"0", "1"}, {"0", "1", "2"}}, subtypes(drain(first)))
+		assert.Nil(t, first.Result())
+		var overflow *BufferOverflowError
+		assert.True(t, errors.As(first.Err(), &overflow))
+	})
+
+	t.Run("Session ends before the result", func(t *testing.T) {
+		client, cli := newTurnClient(t, nil)
+
+		turn, err := client.Send(context.Background(), "one", "")
+		require.NoError(t, err)
+		cli.send(t, systemMessage("partial"))
+		cli.stdout.Close()
+
+		assert.Equal(t, []string{"partial"}, subtypes(drain(turn)))
+		assert.Nil(t, turn.Result())
+		var connErr *CLIConnectionError
+		assert.True(t, errors.As(turn.Err(), &connErr))
+	})
+
+	t.Run("Context cancellation", func(t *testing.T) {
+		client, _ := newTurnClient(t, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		turn, err := client.Send(ctx, "one", "")
+		require.NoError(t, err)
+		cancel()
+
+		drain(turn)
+		assert.ErrorIs(t, turn.Err(), context.Canceled)
+	})
+
+	t.Run("Invalid prompts", func(t *testing.T) {
+		client, _ := newTurnClient(t, nil)
+
+		_, err := client.Send(context.Background(), []map[string]interface{}{}, "")
+		assert.Error(t, err)
+		_, err = client.Send(context.Background(), 42, "")
+		assert.Error(t, err)
+
+		_, err = (&Client{}).Send(context.Background(), "one", "")
+		var connErr *CLIConnectionError
+		assert.True(t, errors.As(err, &connErr))
+	})
+}
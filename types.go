@@ -1,7 +1,9 @@
 package claudesdk
 
 import (
+	"context"
 	"encoding/json"
+	"time"
 )
 
 // PermissionMode represents different permission modes for Claude
@@ -21,6 +23,7 @@ const (
 	MCPServerTypeStdio MCPServerType = "stdio"
 	MCPServerTypeSSE   MCPServerType = "sse"
 	MCPServerTypeHTTP  MCPServerType = "http"
+	MCPServerTypeSDK   MCPServerType = "sdk"
 )
 
 // MCPStdioServerConfig represents MCP stdio server configuration
@@ -45,6 +48,13 @@ type MCPHTTPServerConfig struct {
 	Headers map[string]string `json:"headers,omitempty"`
 }
 
+// MCPSDKServerConfig represents an in-process MCP server created with CreateSDKMCPServer
+type MCPSDKServerConfig struct {
+	Type     MCPServerType `json:"type"`
+	Name     string        `json:"name"`
+	Instance *SDKMCPServer `json:"-"`
+}
+
 // MCPServerConfig represents any MCP server configuration
 type MCPServerConfig interface {
 	isMCPServerConfig()
@@ -53,6 +63,7 @@ type MCPServerConfig interface {
 func (MCPStdioServerConfig) isMCPServerConfig() {}
 func (MCPSSEServerConfig) isMCPServerConfig()   {}
 func (MCPHTTPServerConfig) isMCPServerConfig()  {}
+func (MCPSDKServerConfig) isMCPServerConfig()   {}
 
 // ContentBlock represents a block of content in a message
 type ContentBlock interface {
@@ -92,6 +103,62 @@ type ToolResultBlock struct {
 
 func (ToolResultBlock) isContentBlock() {}
 
+// BlockSource describes where the data of an image or document block comes from
+type BlockSource struct {
+	Type      string `json:"type"` // "base64", "url" or "text"
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// ImageBlock represents image content
+type ImageBlock struct {
+	Source BlockSource `json:"source"`
+}
+
+func (ImageBlock) isContentBlock() {}
+
+// DocumentBlock represents document content such as a PDF
+type DocumentBlock struct {
+	Source BlockSource `json:"source"`
+	Title  string      `json:"title,omitempty"`
+}
+
+func (DocumentBlock) isContentBlock() {}
+
+// RedactedThinkingBlock represents thinking content that was encrypted for safety
+type RedactedThinkingBlock struct {
+	Data string `json:"data"`
+}
+
+func (RedactedThinkingBlock) isContentBlock() {}
+
+// ServerToolUseBlock represents a tool run by the API itself, such as web search
+type ServerToolUseBlock struct {
+	ID    string                 `json:"id"`
+	Name  string                 `json:"name"`
+	Input map[string]interface{} `json:"input"`
+}
+
+func (ServerToolUseBlock) isContentBlock() {}
+
+// WebSearchToolResultBlock represents the results of a server-side web search
+type WebSearchToolResultBlock struct {
+	ToolUseID string      `json:"tool_use_id"`
+	Content   interface{} `json:"content"` // list of results or an error object
+}
+
+func (WebSearchToolResultBlock) isContentBlock() {}
+
+// UnknownBlock holds a content block of a type this SDK doesn't know yet
+type UnknownBlock struct {
+	Type string                 `json:"type"`
+	Data map[string]interface{} `json:"data"`
+	Raw  json.RawMessage        `json:"-"`
+}
+
+func (UnknownBlock) isContentBlock() {}
+
 // Message represents any message type
 type Message interface {
 	isMessage()
@@ -99,15 +166,22 @@ type Message interface {
 
 // UserMessage represents a user message
 type UserMessage struct {
-	Content interface{} `json:"content"` // string or []ContentBlock
+	Content         interface{}     `json:"content"` // string or []ContentBlock
+	SessionID       string          `json:"session_id,omitempty"`
+	ParentToolUseID *string         `json:"parent_tool_use_id,omitempty"` // set inside a subagent
+	Raw             json.RawMessage `json:"-"`                            // original JSON from the CLI
 }
 
 func (UserMessage) isMessage() {}
 
 // AssistantMessage represents an assistant message with content blocks
 type AssistantMessage struct {
-	Content []ContentBlock `json:"content"`
-	Model   string         `json:"model"`
+	Content         []ContentBlock  `json:"content"`
+	Model           string          `json:"model"`
+	Usage           *Usage          `json:"usage,omitempty"`
+	SessionID       string          `json:"session_id,omitempty"`
+	ParentToolUseID *string         `json:"parent_tool_use_id,omitempty"` // set inside a subagent
+	Raw             json.RawMessage `json:"-"`                            // original JSON from the CLI
 }
 
 func (AssistantMessage) isMessage() {}
@@ -116,29 +190,183 @@ func (AssistantMessage) isMessage() {}
 type SystemMessage struct {
 	Subtype string                 `json:"subtype"`
 	Data    map[string]interface{} `json:"data"`
+	Raw     json.RawMessage        `json:"-"` // original JSON from the CLI
 }
 
 func (SystemMessage) isMessage() {}
 
+// MCPServerStatus reports the connection status of an MCP server
+type MCPServerStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // "connected", "failed", "needs-auth" or "pending"
+}
+
+// InitMessage is the system "init" message sent at the start of a session.
+// The embedded SystemMessage keeps the complete raw data.
+type InitMessage struct {
+	SystemMessage
+	SessionID         string
+	CWD               string
+	Model             string
+	PermissionMode    PermissionMode
+	Tools             []string
+	SlashCommands     []string
+	MCPServers        []MCPServerStatus
+	APIKeySource      string
+	ClaudeCodeVersion string
+	OutputStyle       string
+}
+
+// MCPServer returns the status of the named MCP server
+func (m *InitMessage) MCPServer(name string) (MCPServerStatus, bool) {
+	for _, server := range m.MCPServers {
+		if server.Name == name {
+			return server, true
+		}
+	}
+	return MCPServerStatus{}, false
+}
+
+// FailedMCPServers returns the MCP servers that are not connected
+func (m *InitMessage) FailedMCPServers() []MCPServerStatus {
+	var failed []MCPServerStatus
+	for _, server := range m.MCPServers {
+		if server.Status != "connected" {
+			failed = append(failed, server)
+		}
+	}
+	return failed
+}
+
+// CompactBoundaryMessage marks where the conversation history was compacted
+type CompactBoundaryMessage struct {
+	SystemMessage
+	SessionID string
+	Trigger   string // "manual" or "auto"
+	PreTokens int    // tokens in the context before compaction
+}
+
+// APIRetryMessage reports that an API request failed and is being retried
+type APIRetryMessage struct {
+	SystemMessage
+	SessionID    string
+	Attempt      int
+	MaxRetries   int
+	RetryDelayMS int
+	ErrorStatus  int
+	Error        string
+}
+
 // ResultMessage represents a result message with cost and usage information
 type ResultMessage struct {
-	Subtype        string                 `json:"subtype"`
-	DurationMS     int                    `json:"duration_ms"`
-	DurationAPIMS  int                    `json:"duration_api_ms"`
-	IsError        bool                   `json:"is_error"`
-	NumTurns       int                    `json:"num_turns"`
-	SessionID      string                 `json:"session_id"`
-	TotalCostUSD   *float64               `json:"total_cost_usd,omitempty"`
-	Usage          map[string]interface{} `json:"usage,omitempty"`
-	Result         *string                `json:"result,omitempty"`
+	Subtype           string                `json:"subtype"`
+	DurationMS        int                   `json:"duration_ms"`
+	DurationAPIMS     int                   `json:"duration_api_ms"`
+	IsError           bool                  `json:"is_error"`
+	NumTurns          int                   `json:"num_turns"`
+	SessionID         string                `json:"session_id"`
+	TotalCostUSD      *float64              `json:"total_cost_usd,omitempty"`
+	Usage             *Usage                `json:"usage,omitempty"`
+	Result            *string               `json:"result,omitempty"`
+	ModelUsage        map[string]ModelUsage `json:"modelUsage,omitempty"`
+	PermissionDenials []PermissionDenial    `json:"permission_denials,omitempty"`
+	Raw               json.RawMessage       `json:"-"` // original JSON from the CLI
 }
 
 func (ResultMessage) isMessage() {}
 
+// Result subtypes reported by the CLI
+const (
+	ResultSubtypeSuccess              = "success"
+	ResultSubtypeErrorMaxTurns        = "error_max_turns"
+	ResultSubtypeErrorDuringExecution = "error_during_execution"
+	ResultSubtypeErrorMaxBudgetUSD    = "error_max_budget_usd"
+)
+
+// Err converts a failed result into an error, or returns nil on success or
+// for a nil result. Known subtypes map to *MaxTurnsError, *ExecutionError
+// and *MaxBudgetError, which all unwrap to *ResultError; any other failure
+// is a *ResultError.
+func (m *ResultMessage) Err() error {
+	if m == nil || (m.Subtype == ResultSubtypeSuccess && !m.IsError) {
+		return nil
+	}
+
+	base := newResultError(m)
+	switch m.Subtype {
+	case ResultSubtypeErrorMaxTurns:
+		return &MaxTurnsError{ResultError: base}
+	case ResultSubtypeErrorDuringExecution:
+		return &ExecutionError{ResultError: base}
+	case ResultSubtypeErrorMaxBudgetUSD:
+		return &MaxBudgetError{ResultError: base}
+	default:
+		return &base
+	}
+}
+
+// StreamEvent is a partial-message update, emitted when
+// ClaudeCodeOptions.IncludePartialMessages is set. It wraps one event of
+// the Anthropic streaming API; use a PartialMessageAssembler to turn a
+// sequence of them into content blocks.
+type StreamEvent struct {
+	UUID            string  `json:"uuid"`
+	SessionID       string  `json:"session_id"`
+	ParentToolUseID *string `json:"parent_tool_use_id,omitempty"`
+	// EventType is the API event type, e.g. "message_start",
+	// "content_block_delta" or "message_stop"
+	EventType string `json:"-"`
+	// Index is the content block index for content_block_* events
+	Index int `json:"-"`
+	// Delta is set for content_block_delta events
+	Delta *StreamDelta `json:"-"`
+	// Event is the raw API event
+	Event map[string]interface{} `json:"event"`
+	Raw   json.RawMessage        `json:"-"` // original JSON from the CLI
+}
+
+func (StreamEvent) isMessage() {}
+
+// StreamDelta is the incremental content of a content_block_delta event
+type StreamDelta struct {
+	Type        string `json:"type"` // "text_delta", "thinking_delta", "input_json_delta" or "signature_delta"
+	Text        string `json:"text,omitempty"`
+	Thinking    string `json:"thinking,omitempty"`
+	PartialJSON string `json:"partial_json,omitempty"`
+	Signature   string `json:"signature,omitempty"`
+}
+
+// RawMessage is delivered in place of a message the SDK could not parse,
+// when ClaudeCodeOptions.ParseErrorPolicy is ParseErrorDeliverRaw
+type RawMessage struct {
+	Type string                 `json:"type"`
+	Data map[string]interface{} `json:"data"`
+	Raw  json.RawMessage        `json:"-"` // original JSON from the CLI
+	Err  error                  `json:"-"` // why parsing failed
+}
+
+func (RawMessage) isMessage() {}
+
+// ParseErrorPolicy controls what happens to CLI messages that fail to parse
+type ParseErrorPolicy string
+
+const (
+	// ParseErrorSkip drops the message (the default)
+	ParseErrorSkip ParseErrorPolicy = "skip"
+	// ParseErrorDeliverRaw delivers a *RawMessage carrying the original JSON
+	ParseErrorDeliverRaw ParseErrorPolicy = "raw"
+	// ParseErrorStrict ends the stream with a *MessageParseError
+	ParseErrorStrict ParseErrorPolicy = "strict"
+)
+
 // ClaudeCodeOptions represents query options for Claude SDK
 type ClaudeCodeOptions struct {
 	AllowedTools              []string                   `json:"allowed_tools,omitempty"`
+	// MaxThinkingTokens is sent as --max-thinking-tokens when set; 0 leaves
+	// thinking to the CLI. NewClaudeCodeOptions sets 8000, so default
+	// options turn thinking on, and pay for it, on every turn.
 	MaxThinkingTokens         int                        `json:"max_thinking_tokens,omitempty"`
+	Thinking                  *ThinkingConfig            `json:"-"` // Overrides MaxThinkingTokens
 	SystemPrompt              *string                    `json:"system_prompt,omitempty"`
 	AppendSystemPrompt        *string                    `json:"append_system_prompt,omitempty"`
 	MCPServers                map[string]MCPServerConfig `json:"mcp_servers,omitempty"`
@@ -153,10 +381,55 @@ type ClaudeCodeOptions struct {
 	CWD                       *string                    `json:"cwd,omitempty"`
 	Settings                  *string                    `json:"settings,omitempty"`
 	AddDirs                   []string                   `json:"add_dirs,omitempty"`
+	IncludePartialMessages    bool                       `json:"include_partial_messages,omitempty"` // Emit StreamEvent messages
 	ExtraArgs                 map[string]*string         `json:"-"` // Pass arbitrary CLI flags
+
+	// Env sets environment variables for the CLI subprocess only, on top of
+	// the inherited environment. With ClearEnv the inherited environment is
+	// dropped and the subprocess sees only Env, so it must include anything
+	// the CLI needs to run, such as PATH and HOME.
+	Env                       map[string]string          `json:"-"`
+	ClearEnv                  bool                       `json:"-"`
+
+	// ShutdownGracePeriod bounds each step of the shutdown sequence: waiting
+	// for the CLI to exit after stdin is closed, then after SIGTERM, before
+	// it is killed. Zero means DefaultShutdownGracePeriod.
+	ShutdownGracePeriod       time.Duration              `json:"-"`
+
+	// On Linux the CLI runs in its own process group, and shutdown signals
+	// reach every process in it, including tool shells and MCP servers.
+	// DisableProcessGroup signals only the CLI itself.
+	DisableProcessGroup       bool                       `json:"-"`
+
+	// CanUseTool is consulted over the control protocol before each tool
+	// call. It cannot be combined with PermissionPromptToolName.
+	CanUseTool                CanUseToolFunc             `json:"-"`
+
+	// Hooks registers Go callbacks for CLI hook events. They are invoked
+	// over the control protocol, which requires streaming input.
+	Hooks                     map[HookEvent][]HookMatcher `json:"-"`
+
+	// MaxMessageSize limits the size in bytes of a single message from the
+	// CLI. Zero means DefaultMaxMessageSize and a negative value removes the
+	// limit. Larger messages are skipped and reported as *MessageTooLargeError.
+	MaxMessageSize            int                         `json:"-"`
+
+	// MessageBufferSize limits how many messages each Subscription or Turn
+	// queues for a reader that has fallen behind, and how many a Client
+	// holds while no receiver is attached. Zero means
+	// DefaultMessageBufferSize and a negative value removes the limit. A
+	// subscription or turn that overflows ends with *BufferOverflowError;
+	// held messages past the limit are dropped, oldest first.
+	MessageBufferSize         int                         `json:"-"`
+
+	// ParseErrorPolicy decides what happens to messages that fail to parse.
+	// OnParseError, if set, is called for every failure regardless of policy.
+	ParseErrorPolicy          ParseErrorPolicy            `json:"-"`
+	OnParseError              func(err *MessageParseError) `json:"-"`
 }
 
-// NewClaudeCodeOptions creates a new ClaudeCodeOptions with defaults
+// NewClaudeCodeOptions creates a new ClaudeCodeOptions with defaults,
+// including an 8000-token thinking budget
 func NewClaudeCodeOptions() *ClaudeCodeOptions {
 	return &ClaudeCodeOptions{
 		AllowedTools:      []string{},
@@ -168,7 +441,11 @@ func NewClaudeCodeOptions() *ClaudeCodeOptions {
 	}
 }
 
-// MessageData represents the structure of messages sent to/from Claude
+// MessageData represents the structure of messages sent to/from Claude.
+//
+// Messages received from SubprocessCLITransport only carry the envelope
+// (Type, Subtype, SessionID, ParentToolUseID and UUID) and Raw; the rest is
+// decoded once, straight into a typed Message, by ParseMessageFromJSON.
 type MessageData struct {
 	Type             string                 `json:"type"`
 	Message          map[string]interface{} `json:"message,omitempty"`
@@ -185,6 +462,11 @@ type MessageData struct {
 	TotalCostUSD     *float64               `json:"total_cost_usd,omitempty"`
 	Usage            map[string]interface{} `json:"usage,omitempty"`
 	Result           *string                `json:"result,omitempty"`
+	UUID             string                 `json:"uuid,omitempty"`
+	Event            map[string]interface{} `json:"event,omitempty"`
+
+	// Raw is the original JSON line received from the CLI, if any
+	Raw              json.RawMessage        `json:"-"`
 }
 
 // Transport defines the interface for communication with Claude
@@ -194,6 +476,12 @@ type Transport interface {
 	SendRequest(messages []MessageData, metadata map[string]interface{}) error
 	ReceiveMessages() (<-chan MessageData, error)
 	Interrupt() error
+	SendControlRequest(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error)
+	// Done is closed once the CLI has exited; Err then reports why
+	Done() <-chan struct{}
+	Err() error
+	// StreamErr reports the first line of output that was skipped
+	StreamErr() error
 }
 
 // Custom JSON marshaling for messages
@@ -230,6 +518,17 @@ func (m SystemMessage) MarshalJSON() ([]byte, error) {
 	})
 }
 
+func (m StreamEvent) MarshalJSON() ([]byte, error) {
+	type Alias StreamEvent
+	return json.Marshal(&struct {
+		Type string `json:"type"`
+		*Alias
+	}{
+		Type:  "stream_event",
+		Alias: (*Alias)(&m),
+	})
+}
+
 func (m ResultMessage) MarshalJSON() ([]byte, error) {
 	type Alias ResultMessage
 	return json.Marshal(&struct {
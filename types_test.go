@@ -1,9 +1,11 @@
 package claudesdk
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMessageTypes(t *testing.T) {
@@ -78,6 +80,62 @@ func TestMessageTypes(t *testing.T) {
 	})
 }
 
+func TestResultMessageErr(t *testing.T) {
+	result := func(subtype string, isError bool) *ResultMessage {
+		return &ResultMessage{
+			Subtype:   subtype,
+			IsError:   isError,
+			NumTurns:  10,
+			SessionID: "session-123",
+		}
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		assert.NoError(t, result("success", false).Err())
+	})
+
+	t.Run("Max turns", func(t *testing.T) {
+		err := result("error_max_turns", true).Err()
+		var maxTurns *MaxTurnsError
+		assert.True(t, errors.As(err, &maxTurns))
+		assert.Equal(t, 10, maxTurns.NumTurns)
+		assert.Equal(t, "session-123", maxTurns.SessionID)
+		assert.Contains(t, err.Error(), "maximum number of turns")
+	})
+
+	t.Run("Error during execution", func(t *testing.T) {
+		var execErr *ExecutionError
+		assert.True(t, errors.As(result("error_during_execution", true).Err(), &execErr))
+	})
+
+	t.Run("Max budget", func(t *testing.T) {
+		var budgetErr *MaxBudgetError
+		assert.True(t, errors.As(result("error_max_budget_usd", true).Err(), &budgetErr))
+	})
+
+	t.Run("Any failure is a ResultError", func(t *testing.T) {
+		for _, subtype := range []string{"error_max_turns", "error_during_execution", "error_max_budget_usd"} {
+			var resultErr *ResultError
+			require.True(t, errors.As(result(subtype, true).Err(), &resultErr), subtype)
+			assert.Equal(t, subtype, resultErr.Subtype)
+		}
+	})
+
+	t.Run("Nil result", func(t *testing.T) {
+		var msg *ResultMessage
+		assert.NoError(t, msg.Err())
+	})
+
+	t.Run("Success flagged as error", func(t *testing.T) {
+		msg := result("success", true)
+		msg.Result = String("API Error: 500")
+		err := msg.Err()
+		var resultErr *ResultError
+		assert.True(t, errors.As(err, &resultErr))
+		assert.Contains(t, err.Error(), "API Error: 500")
+	})
+}
+
 func TestOptions(t *testing.T) {
 	t.Run("Default options", func(t *testing.T) {
 		options := NewClaudeCodeOptions()
@@ -0,0 +1,132 @@
+package claudesdk
+
+import "encoding/json"
+
+// Usage is the token usage of an API response or a whole query
+type Usage struct {
+	InputTokens              int              `json:"input_tokens"`
+	OutputTokens             int              `json:"output_tokens"`
+	CacheCreationInputTokens int              `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int              `json:"cache_read_input_tokens"`
+	ServerToolUse            *ServerToolUsage `json:"server_tool_use,omitempty"`
+	ServiceTier              string           `json:"service_tier,omitempty"`
+	// Raw holds the usage object as sent by the CLI, including fields not
+	// modeled here
+	Raw map[string]interface{} `json:"-"`
+}
+
+// ServerToolUsage counts requests made by server-side tools
+type ServerToolUsage struct {
+	WebSearchRequests int `json:"web_search_requests"`
+}
+
+// TotalTokens returns the sum of all token categories
+func (u Usage) TotalTokens() int {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+// Add returns the sum of two usages. Raw and ServiceTier are not carried over.
+func (u Usage) Add(other Usage) Usage {
+	sum := Usage{
+		InputTokens:              u.InputTokens + other.InputTokens,
+		OutputTokens:             u.OutputTokens + other.OutputTokens,
+		CacheCreationInputTokens: u.CacheCreationInputTokens + other.CacheCreationInputTokens,
+		CacheReadInputTokens:     u.CacheReadInputTokens + other.CacheReadInputTokens,
+	}
+	if u.ServerToolUse != nil || other.ServerToolUse != nil {
+		sum.ServerToolUse = &ServerToolUsage{}
+		if u.ServerToolUse != nil {
+			sum.ServerToolUse.WebSearchRequests += u.ServerToolUse.WebSearchRequests
+		}
+		if other.ServerToolUse != nil {
+			sum.ServerToolUse.WebSearchRequests += other.ServerToolUse.WebSearchRequests
+		}
+	}
+	return sum
+}
+
+// ModelUsage is the usage and cost attributed to a single model in a query
+type ModelUsage struct {
+	InputTokens              int     `json:"inputTokens"`
+	OutputTokens             int     `json:"outputTokens"`
+	CacheReadInputTokens     int     `json:"cacheReadInputTokens"`
+	CacheCreationInputTokens int     `json:"cacheCreationInputTokens"`
+	WebSearchRequests        int     `json:"webSearchRequests"`
+	CostUSD                  float64 `json:"costUSD"`
+	ContextWindow            int     `json:"contextWindow,omitempty"`
+}
+
+// Add returns the sum of two model usages, keeping the larger context window
+func (m ModelUsage) Add(other ModelUsage) ModelUsage {
+	sum := ModelUsage{
+		InputTokens:              m.InputTokens + other.InputTokens,
+		OutputTokens:             m.OutputTokens + other.OutputTokens,
+		CacheReadInputTokens:     m.CacheReadInputTokens + other.CacheReadInputTokens,
+		CacheCreationInputTokens: m.CacheCreationInputTokens + other.CacheCreationInputTokens,
+		WebSearchRequests:        m.WebSearchRequests + other.WebSearchRequests,
+		CostUSD:                  m.CostUSD + other.CostUSD,
+		ContextWindow:            m.ContextWindow,
+	}
+	if other.ContextWindow > sum.ContextWindow {
+		sum.ContextWindow = other.ContextWindow
+	}
+	return sum
+}
+
+// PermissionDenial records a tool call that was denied during a query
+type PermissionDenial struct {
+	ToolName  string                 `json:"tool_name"`
+	ToolUseID string                 `json:"tool_use_id"`
+	ToolInput map[string]interface{} `json:"tool_input"`
+}
+
+// UsageTotals aggregates usage and cost over several results
+type UsageTotals struct {
+	Usage
+	TotalCostUSD float64
+	NumTurns     int
+	NumResults   int
+	ByModel      map[string]ModelUsage
+}
+
+// SumUsage adds up the usage, cost and per-model breakdown of the given
+// results. Nil results are ignored.
+//
+// Example:
+//
+//	totals := SumUsage(results...)
+//	fmt.Printf("%d input, %d output tokens, $%.4f\n",
+//	    totals.InputTokens, totals.OutputTokens, totals.TotalCostUSD)
+func SumUsage(results ...*ResultMessage) UsageTotals {
+	totals := UsageTotals{
+		ByModel: make(map[string]ModelUsage),
+	}
+
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		totals.NumResults++
+		totals.NumTurns += result.NumTurns
+		if result.Usage != nil {
+			totals.Usage = totals.Usage.Add(*result.Usage)
+		}
+		if result.TotalCostUSD != nil {
+			totals.TotalCostUSD += *result.TotalCostUSD
+		}
+		for model, usage := range result.ModelUsage {
+			totals.ByModel[model] = totals.ByModel[model].Add(usage)
+		}
+	}
+
+	return totals
+}
+
+// UnmarshalJSON decodes a usage object, keeping the complete object in Raw
+func (u *Usage) UnmarshalJSON(data []byte) error {
+	type plain Usage
+	if err := json.Unmarshal(data, (*plain)(u)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &u.Raw)
+}
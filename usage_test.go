@@ -0,0 +1,111 @@
+package claudesdk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const resultWithUsageJSON = `{
+	"type": "result",
+	"subtype": "success",
+	"duration_ms": 1500,
+	"duration_api_ms": 1200,
+	"is_error": false,
+	"num_turns": 2,
+	"session_id": "session-123",
+	"total_cost_usd": 0.25,
+	"usage": {
+		"input_tokens": 100,
+		"output_tokens": 50,
+		"cache_creation_input_tokens": 2000,
+		"cache_read_input_tokens": 8000,
+		"server_tool_use": {"web_search_requests": 1},
+		"service_tier": "standard"
+	},
+	"modelUsage": {
+		"claude-sonnet-4-5": {
+			"inputTokens": 90,
+			"outputTokens": 40,
+			"cacheReadInputTokens": 8000,
+			"cacheCreationInputTokens": 2000,
+			"webSearchRequests": 1,
+			"costUSD": 0.2,
+			"contextWindow": 200000
+		},
+		"claude-haiku-4-5": {
+			"inputTokens": 10,
+			"outputTokens": 10,
+			"costUSD": 0.05
+		}
+	},
+	"permission_denials": [
+		{"tool_name": "Bash", "tool_use_id": "toolu_1", "tool_input": {"command": "rm -rf /"}}
+	]
+}`
+
+func TestResultUsage(t *testing.T) {
+	msg, err := ParseMessageFromJSON([]byte(resultWithUsageJSON))
+	require.NoError(t, err)
+	result := msg.(*ResultMessage)
+
+	require.NotNil(t, result.Usage)
+	assert.Equal(t, 100, result.Usage.InputTokens)
+	assert.Equal(t, 50, result.Usage.OutputTokens)
+	assert.Equal(t, 2000, result.Usage.CacheCreationInputTokens)
+	assert.Equal(t, 8000, result.Usage.CacheReadInputTokens)
+	assert.Equal(t, 1, result.Usage.ServerToolUse.WebSearchRequests)
+	assert.Equal(t, "standard", result.Usage.ServiceTier)
+	assert.Equal(t, 10150, result.Usage.TotalTokens())
+
+	require.Len(t, result.ModelUsage, 2)
+	sonnet := result.ModelUsage["claude-sonnet-4-5"]
+	assert.Equal(t, 90, sonnet.InputTokens)
+	assert.Equal(t, 0.2, sonnet.CostUSD)
+	assert.Equal(t, 200000, sonnet.ContextWindow)
+
+	require.Len(t, result.PermissionDenials, 1)
+	assert.Equal(t, "Bash", result.PermissionDenials[0].ToolName)
+	assert.Equal(t, "rm -rf /", result.PermissionDenials[0].ToolInput["command"])
+}
+
+func TestAssistantUsage(t *testing.T) {
+	msg, err := ParseMessageFromJSON([]byte(`{
+		"type": "assistant",
+		"message": {
+			"model": "claude-sonnet-4-5",
+			"content": [{"type": "text", "text": "Hi"}],
+			"usage": {"input_tokens": 12, "output_tokens": 3}
+		}
+	}`))
+	require.NoError(t, err)
+
+	usage := msg.(*AssistantMessage).Usage
+	require.NotNil(t, usage)
+	assert.Equal(t, 12, usage.InputTokens)
+	assert.Equal(t, 3, usage.OutputTokens)
+	assert.Nil(t, usage.ServerToolUse)
+}
+
+func TestSumUsage(t *testing.T) {
+	msg, err := ParseMessageFromJSON([]byte(resultWithUsageJSON))
+	require.NoError(t, err)
+	first := msg.(*ResultMessage)
+
+	msg, err = ParseMessageFromJSON([]byte(resultWithUsageJSON))
+	require.NoError(t, err)
+	second := msg.(*ResultMessage)
+
+	totals := SumUsage(first, nil, second)
+	assert.Equal(t, 2, totals.NumResults)
+	assert.Equal(t, 4, totals.NumTurns)
+	assert.Equal(t, 200, totals.InputTokens)
+	assert.Equal(t, 100, totals.OutputTokens)
+	assert.Equal(t, 4000, totals.CacheCreationInputTokens)
+	assert.Equal(t, 16000, totals.CacheReadInputTokens)
+	assert.Equal(t, 2, totals.ServerToolUse.WebSearchRequests)
+	assert.InDelta(t, 0.5, totals.TotalCostUSD, 1e-9)
+	assert.Equal(t, 180, totals.ByModel["claude-sonnet-4-5"].InputTokens)
+	assert.InDelta(t, 0.1, totals.ByModel["claude-haiku-4-5"].CostUSD, 1e-9)
+}